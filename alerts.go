@@ -0,0 +1,132 @@
+package splunk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	firedAlertsSuffix = "/services/alerts/fired_alerts"
+	firedAlertSuffix  = "/services/alerts/fired_alerts/%s"
+)
+
+// FiredAlertGroup is a saved search that has triggered alerts
+type FiredAlertGroup struct {
+	Name                string
+	TriggeredAlertCount int `splunk:"triggered_alert_count"`
+}
+
+// FiredAlert is a single triggered instance of an alert
+type FiredAlert struct {
+	// Name is the name of this triggered alert, used to delete it
+	Name            string
+	SavedSearchName string `splunk:"savedsearch_name"`
+	SearchID        string `splunk:"sid"`
+	Severity        int    `splunk:"severity"`
+	AlertType       string `splunk:"alert_type"`
+	TriggerTime     time.Time
+
+	client *Client
+}
+
+// firedAlertsResponse is what splunk returns when fetching fired alerts
+type firedAlertsResponse struct {
+	Entry []struct {
+		Name    string       `json:"name"`
+		Content SearchResult `json:"content"`
+	} `json:"entry"`
+	Paging Paging `json:"paging"`
+}
+
+// ListFiredAlertGroups Lists the saved searches that have triggered alerts
+//
+// Splunk includes a group named "-" that contains every triggered alert
+func (c *Client) ListFiredAlertGroups(ctx context.Context) ([]*FiredAlertGroup, error) {
+	result, err := c.getFiredAlerts(ctx, firedAlertsSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*FiredAlertGroup, 0, len(result.Entry))
+	for _, entry := range result.Entry {
+		group := &FiredAlertGroup{}
+		if err := entry.Content.UnMarshal(group); err != nil {
+			return nil, err
+		}
+		group.Name = entry.Name
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// ListFiredAlerts Lists the triggered instances of an alert group.  Use "-" to list all triggered alerts
+func (c *Client) ListFiredAlerts(ctx context.Context, groupName string) ([]*FiredAlert, error) {
+	result, err := c.getFiredAlerts(ctx, fmt.Sprintf(firedAlertSuffix, url.PathEscape(groupName)))
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := make([]*FiredAlert, 0, len(result.Entry))
+	for _, entry := range result.Entry {
+		alert := &FiredAlert{}
+		if err := entry.Content.UnMarshal(alert); err != nil {
+			return nil, err
+		}
+		alert.Name = entry.Name
+		if triggerTime, err := getFloatValue(entry.Content["trigger_time"]); err == nil {
+			alert.TriggerTime = time.Unix(int64(triggerTime), 0)
+		}
+		alert.client = c
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// getFiredAlerts fetches and parses the fired alerts at the suffix
+func (c *Client) getFiredAlerts(ctx context.Context, suffix string) (*firedAlertsResponse, error) {
+	resp, err := c.BuildResponse(ctx, http.MethodGet, suffix, map[string]string{"count": "0"})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("bad status code: %d", resp.StatusCode)
+	}
+
+	result := firedAlertsResponse{}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %s", err)
+	}
+	return &result, nil
+}
+
+// DeleteFiredAlert Deletes the record of a triggered alert.  This is how an alert is acknowledged in splunk
+func (c *Client) DeleteFiredAlert(ctx context.Context, name string) error {
+	resp, err := c.BuildResponse(ctx, http.MethodDelete, fmt.Sprintf(firedAlertSuffix, url.PathEscape(name)), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("bad status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Search Returns the search job that triggered the alert, to fetch its results
+func (a *FiredAlert) Search() *Search {
+	return &Search{
+		SearchID: a.SearchID,
+		client:   a.client,
+	}
+}
+
+// Acknowledge the alert by deleting the record of it
+func (a *FiredAlert) Acknowledge(ctx context.Context) error {
+	return a.client.DeleteFiredAlert(ctx, a.Name)
+}
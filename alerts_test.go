@@ -0,0 +1,97 @@
+package splunk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ListFiredAlertGroups(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method != http.MethodGet:
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			case req.RequestURI != "/services/alerts/fired_alerts?count=0&output_mode=json":
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			rw.Write([]byte(`{"entry":[{"name":"-","content":{"triggered_alert_count":3}},{"name":"errors","content":{"triggered_alert_count":"2"}}]}`))
+		}))
+		defer server.Close()
+		client := &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		}
+		groups, err := client.ListFiredAlertGroups(context.Background())
+		require.NoError(t, err)
+		require.Len(t, groups, 2)
+		require.Equal(t, "errors", groups[1].Name)
+		require.Equal(t, 2, groups[1].TriggeredAlertCount)
+	})
+}
+
+func TestClient_ListFiredAlerts(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method != http.MethodGet:
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			case req.RequestURI != "/services/alerts/fired_alerts/my%20errors?count=0&output_mode=json":
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			rw.Write([]byte(`{"entry":[{"name":"scheduler__admin__search__errors_at_1600000000_1","content":{"savedsearch_name":"my errors","sid":"scheduler__admin__search__errors_at_1600000000_1","severity":4,"alert_type":"number of events","trigger_time":1600000005}}]}`))
+		}))
+		defer server.Close()
+		client := &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		}
+		alerts, err := client.ListFiredAlerts(context.Background(), "my errors")
+		require.NoError(t, err)
+		require.Len(t, alerts, 1)
+		require.Equal(t, "my errors", alerts[0].SavedSearchName)
+		require.Equal(t, 4, alerts[0].Severity)
+		require.Equal(t, time.Unix(1600000005, 0), alerts[0].TriggerTime)
+		require.Equal(t, "scheduler__admin__search__errors_at_1600000000_1", alerts[0].Search().SearchID)
+	})
+}
+
+func TestFiredAlert_Acknowledge(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method != http.MethodDelete:
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			case req.RequestURI != "/services/alerts/fired_alerts/alert_1?output_mode=json":
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			rw.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		alert := &FiredAlert{
+			Name: "alert_1",
+			client: &Client{
+				config: &Config{
+					BaseURL:    server.URL,
+					HTTPClient: http.DefaultClient,
+				},
+			},
+		}
+		err := alert.Acknowledge(context.Background())
+		require.NoError(t, err)
+	})
+}
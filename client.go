@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -100,6 +101,39 @@ func (c *Client) BuildResponse(ctx context.Context, method, suffix string, param
 	return c.MakeRequest(req)
 }
 
+// BuildJSONResponse is a helper function to make a request with a JSON body, for the endpoints that do not take form parameters.
+//
+// The params are put in the URL and the body is marshaled to JSON.  If body is nil, no body is sent
+func (c *Client) BuildJSONResponse(ctx context.Context, method, suffix string, params map[string]string, body interface{}) (*http.Response, error) {
+	if len(suffix) > 0 && suffix[0] != '/' {
+		suffix = "/" + suffix
+	}
+
+	// Build URL
+	urlValues := url.Values{}
+	urlValues.Add("output_mode", "json")
+	for key, value := range params {
+		urlValues.Add(key, value)
+	}
+	URL := fmt.Sprintf("%s%s?%s", c.config.BaseURL, suffix, urlValues.Encode())
+
+	// Build body
+	bodyBuffer := &bytes.Buffer{}
+	if body != nil {
+		if err := json.NewEncoder(bodyBuffer).Encode(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, URL, bodyBuffer)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	return c.MakeRequest(req)
+}
+
 // MakeRequest adds authentication to the request and performs it
 func (c *Client) MakeRequest(req *http.Request) (*http.Response, error) {
 	req.Header.Add("Authorization", fmt.Sprintf("Basic %s", c.authHeader))
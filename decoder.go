@@ -0,0 +1,109 @@
+package splunk
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrMissingField is the error of a FieldError for a required field that is not in the result
+var ErrMissingField = errors.New("required field is missing")
+
+// ErrUnknownField is the error of a FieldError for a result field that does not fill any struct field
+var ErrUnknownField = errors.New("unknown field")
+
+// Decoder decodes search results into structs, see SearchResult.UnMarshal for how fields are filled.
+//
+// The zero value decodes the same as UnMarshal.
+type Decoder struct {
+	// RequireTaggedFields returns an error for each field with a "splunk" tag that is not in the result,
+	// as if every tagged field had the `required` option
+	RequireTaggedFields bool
+	// DisallowUnknownFields returns an error for each result field that does not fill a struct field.
+	// Internal fields starting with "_", such as "_raw" and "_time", are allowed.
+	DisallowUnknownFields bool
+}
+
+// FieldError is an error decoding a single field
+type FieldError struct {
+	// The field in the result
+	SplunkField string
+	// The field in the struct, empty for unknown fields
+	StructField string
+	// The value in the result, nil for missing fields
+	Value interface{}
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s.  splunk field: %s, struct field: %s, value: %v", e.Err, e.SplunkField, e.StructField, e.Value)
+}
+
+// Unwrap Returns the underlying error
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError is every field error from decoding a result
+type DecodeError struct {
+	Errors []*FieldError
+}
+
+func (e *DecodeError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("%d field errors: %s", len(e.Errors), strings.Join(messages, "; "))
+}
+
+// decodeState tracks a single Decode
+type decodeState struct {
+	decoder *Decoder
+	result  SearchResult
+	// Result fields that filled a struct field, only tracked with DisallowUnknownFields
+	used   map[string]bool
+	errors []*FieldError
+}
+
+// Decode Fills in a struct with the splunk result.  The passed in interface must be a pointer to a struct
+//
+// If any fields fail, the others are still filled and a *DecodeError with every field error is returned
+func (d *Decoder) Decode(result SearchResult, v interface{}) error {
+	valueOf := reflect.ValueOf(v)
+	if valueOf.Kind() != reflect.Ptr || valueOf.IsNil() || valueOf.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("must decode into a pointer to a struct, got %T", v)
+	}
+
+	state := &decodeState{
+		decoder: d,
+		result:  result,
+	}
+	if d.DisallowUnknownFields {
+		state.used = make(map[string]bool, len(result))
+	}
+	state.unmarshalStruct(valueOf.Elem(), getStructPlan(valueOf.Elem().Type()))
+
+	if d.DisallowUnknownFields {
+		unknown := []string{}
+		for key := range result {
+			if !state.used[key] && !strings.HasPrefix(key, "_") {
+				unknown = append(unknown, key)
+			}
+		}
+		sort.Strings(unknown)
+		for _, key := range unknown {
+			state.errors = append(state.errors, &FieldError{SplunkField: key, Value: result[key], Err: ErrUnknownField})
+		}
+	}
+
+	if len(state.errors) > 0 {
+		return &DecodeError{Errors: state.errors}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package splunk
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoder_Decode(t *testing.T) {
+	type TestStruct struct {
+		Host     string `splunk:"host,required"`
+		Count    int    `splunk:"count"`
+		Latency  int    `splunk:"latency"`
+		Untagged string
+	}
+
+	t.Run("all errors", func(t *testing.T) {
+		testStruct := TestStruct{}
+		err := SearchResult{"count": "many", "latency": "slow", "Untagged": "ok"}.UnMarshal(&testStruct)
+		decodeErr := &DecodeError{}
+		require.True(t, errors.As(err, &decodeErr))
+		require.Len(t, decodeErr.Errors, 3)
+		require.True(t, errors.Is(decodeErr.Errors[0], ErrMissingField))
+		require.Equal(t, "host", decodeErr.Errors[0].SplunkField)
+		require.Equal(t, "count", decodeErr.Errors[1].SplunkField)
+		require.Equal(t, "many", decodeErr.Errors[1].Value)
+		require.Equal(t, "Latency", decodeErr.Errors[2].StructField)
+		// The good fields are still filled
+		require.Equal(t, "ok", testStruct.Untagged)
+	})
+
+	t.Run("require tagged", func(t *testing.T) {
+		decoder := &Decoder{RequireTaggedFields: true}
+		err := decoder.Decode(SearchResult{"host": "web01", "count": "1"}, &TestStruct{})
+		decodeErr := &DecodeError{}
+		require.True(t, errors.As(err, &decodeErr))
+		require.Len(t, decodeErr.Errors, 1)
+		require.Equal(t, "latency", decodeErr.Errors[0].SplunkField)
+
+		require.NoError(t, decoder.Decode(SearchResult{"host": "web01", "count": "1", "latency": "2"}, &TestStruct{}))
+	})
+
+	t.Run("disallow unknown", func(t *testing.T) {
+		decoder := &Decoder{DisallowUnknownFields: true}
+		err := decoder.Decode(SearchResult{"host": "web01", "_raw": "raw", "extra": "1", "another": "2"}, &TestStruct{})
+		decodeErr := &DecodeError{}
+		require.True(t, errors.As(err, &decodeErr))
+		require.Len(t, decodeErr.Errors, 2)
+		require.Equal(t, "another", decodeErr.Errors[0].SplunkField)
+		require.True(t, errors.Is(decodeErr.Errors[1], ErrUnknownField))
+		require.Equal(t, "extra", decodeErr.Errors[1].SplunkField)
+	})
+
+	t.Run("optional nested struct", func(t *testing.T) {
+		type Endpoint struct {
+			IP   string `splunk:"ip,required"`
+			Port int    `splunk:"port"`
+		}
+		type NestedStruct struct {
+			Src *Endpoint `splunk:"src"`
+		}
+		testStruct := NestedStruct{}
+		require.NoError(t, SearchResult{}.UnMarshal(&testStruct))
+		require.Nil(t, testStruct.Src)
+		require.Error(t, SearchResult{"src.port": "80"}.UnMarshal(&testStruct))
+	})
+
+	t.Run("not a struct pointer", func(t *testing.T) {
+		require.Error(t, (&Decoder{}).Decode(SearchResult{}, TestStruct{}))
+	})
+}
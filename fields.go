@@ -0,0 +1,119 @@
+package splunk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+const (
+	defaultFieldDiscoveryEarliest  = "-24h"
+	defaultFieldDiscoveryMaxEvents = 10000
+	defaultFieldDiscoveryMaxValues = 10
+)
+
+// FieldDiscoveryOptions choose the events to discover fields in
+type FieldDiscoveryOptions struct {
+	// Index and source type of the events, empty for any
+	Index      string
+	SourceType string
+	// Time range as time modifiers, such as "-24h" or a RelativeTime's String.  Default: the last 24 hours
+	Earliest string
+	Latest   string
+	// Number of events sampled, the most recent first.  Default: 10000
+	MaxEvents int
+	// Number of the most common values returned for each field.  Default: 10
+	MaxValues int
+	// Params are any other parameters for the job, see CreateSearchJob
+	Params map[string]string
+}
+
+// DiscoveredField is a field found in the events, with counts of its values
+type DiscoveredField struct {
+	Name string `splunk:"field"`
+	// Number of events with the field
+	Count         int64 `splunk:"count"`
+	DistinctCount int64 `splunk:"distinct_count"`
+	// Whether DistinctCount is exact instead of estimated
+	IsExact bool `splunk:"is_exact"`
+	// Number of events where the field is a number
+	NumericCount int64 `splunk:"numeric_count"`
+	// The most common values, most common first
+	Values FieldValueCounts `splunk:"values"`
+}
+
+// FieldValueCount is the number of events with a value of a field
+type FieldValueCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// FieldValueCounts are the values of a field, decoded from the JSON splunk returns them as
+type FieldValueCounts []FieldValueCount
+
+// UnmarshalSplunk Decodes the JSON list of values
+func (f *FieldValueCounts) UnmarshalSplunk(value interface{}) error {
+	text, ok := value.(string)
+	if !ok || text == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(text), f)
+}
+
+// DiscoverFields Finds the fields of the matching events with the fieldsummary command, the most common fields first
+func (c *Client) DiscoverFields(ctx context.Context, options *FieldDiscoveryOptions) ([]*DiscoveredField, error) {
+	maxEvents := options.MaxEvents
+	if maxEvents <= 0 {
+		maxEvents = defaultFieldDiscoveryMaxEvents
+	}
+	maxValues := options.MaxValues
+	if maxValues <= 0 {
+		maxValues = defaultFieldDiscoveryMaxValues
+	}
+
+	builder := NewQuery()
+	if options.Index != "" {
+		builder.Index(options.Index)
+	}
+	if options.SourceType != "" {
+		builder.Where("sourcetype", options.SourceType)
+	}
+	query, err := builder.
+		Pipe("head $count$", map[string]interface{}{"count": maxEvents}).
+		Pipe("fieldsummary maxvals=$count$", map[string]interface{}{"count": maxValues}).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{}
+	for key, value := range options.Params {
+		params[key] = value
+	}
+	params["earliest_time"] = defaultFieldDiscoveryEarliest
+	if options.Earliest != "" {
+		params["earliest_time"] = options.Earliest
+	}
+	if options.Latest != "" {
+		params["latest_time"] = options.Latest
+	}
+
+	search, err := c.CreateSearchJob(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+	defer search.Delete(context.Background())
+	if err := search.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("failed waiting on field discovery: %s", err)
+	}
+
+	fields := []*DiscoveredField{}
+	if err := search.DecodeAllResults(ctx, &fields, nil); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].Count > fields[j].Count
+	})
+	return fields, nil
+}
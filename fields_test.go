@@ -0,0 +1,64 @@
+package splunk
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_DiscoverFields(t *testing.T) {
+	var job url.Values
+	deleted := false
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == http.MethodPost && req.URL.Path == "/services/search/jobs":
+			b, _ := ioutil.ReadAll(req.Body)
+			job, _ = url.ParseQuery(string(b))
+			rw.WriteHeader(http.StatusCreated)
+			rw.Write([]byte(`{"sid":"fields_1"}`))
+		case req.Method == http.MethodDelete:
+			deleted = true
+		case req.URL.Path == "/services/search/jobs/fields_1/results_preview":
+			if req.URL.Query().Get("offset") != "0" {
+				rw.Write([]byte(`{"preview":false,"results":[]}`))
+				return
+			}
+			rw.Write([]byte(`{"preview":false,"results":[
+				{"field":"status","count":"80","distinct_count":"3","is_exact":"1","numeric_count":"80","values":"[{\"value\":\"200\",\"count\":70},{\"value\":\"404\",\"count\":10}]"},
+				{"field":"host","count":"100","distinct_count":"2","is_exact":"1","numeric_count":"0","values":"[{\"value\":\"web-1\",\"count\":60}]"}
+			]}`))
+		default:
+			rw.Write([]byte(`{"entry":[{"content":{"dispatchState":"DONE"}}]}`))
+		}
+	}))
+	defer server.Close()
+	client := &Client{
+		config: &Config{
+			BaseURL:    server.URL,
+			HTTPClient: http.DefaultClient,
+		},
+	}
+
+	fields, err := client.DiscoverFields(context.Background(), &FieldDiscoveryOptions{Index: "web", SourceType: "access_combined", Latest: "now"})
+	require.NoError(t, err)
+	require.Equal(t, `search index="web" sourcetype="access_combined" | head 10000 | fieldsummary maxvals=10`, job.Get("search"))
+	require.Equal(t, "-24h", job.Get("earliest_time"))
+	require.Equal(t, "now", job.Get("latest_time"))
+	require.True(t, deleted)
+
+	require.Len(t, fields, 2)
+	require.Equal(t, &DiscoveredField{
+		Name:          "host",
+		Count:         100,
+		DistinctCount: 2,
+		IsExact:       true,
+		Values:        FieldValueCounts{{Value: "web-1", Count: 60}},
+	}, fields[0])
+	require.Equal(t, "status", fields[1].Name)
+	require.Equal(t, FieldValueCounts{{Value: "200", Count: 70}, {Value: "404", Count: 10}}, fields[1].Values)
+}
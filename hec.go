@@ -0,0 +1,514 @@
+package splunk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	hecEventSuffix = "/services/collector/event"
+	hecRawSuffix   = "/services/collector/raw"
+
+	defaultHECBatchCount    = 100
+	defaultHECBatchSize     = 1024 * 1024
+	defaultHECFlushInterval = time.Second * 5
+)
+
+// HECClient is a splunk HTTP Event Collector client used to send events to splunk.
+//
+// Events added with Add are batched and sent when the batch reaches BatchCount events, BatchSize bytes,
+// or every FlushInterval.  Call Close when done to send any remaining events.
+type HECClient struct {
+	config *HECConfig
+
+	// The current batch being filled
+	lock  sync.Mutex
+	batch *hecBatch
+
+	// Batches waiting on an indexer acknowledgement, by a local ID since splunk can reuse ack IDs
+	ackLock       sync.Mutex
+	pending       map[uint64]*pendingBatch
+	nextPendingID uint64
+
+	// Optional on-disk spool of batches that are not delivered yet
+	spool *hecSpool
+	// Signals the spool loop that a batch was written
+	spooled chan struct{}
+	// Stops a send in progress in the spool loop
+	cancelSpool context.CancelFunc
+
+	closed bool
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// HECConfig for a HEC connection
+//
+// If HTTPClient is nil, the default will be used.  Zero batching values use the defaults.
+type HECConfig struct {
+	// Used if you want to use a custom HTTP client
+	HTTPClient *http.Client
+
+	// Base URL of your HEC endpoint.
+	// Do not include a `/`` at the end.
+	// ex: https://localhost:8088
+	BaseURL string
+
+	// HEC token
+	Token string
+
+	// Maximum number of events in a batch.  Default: 100
+	BatchCount int
+	// Maximum size in bytes of a batch before compression.  Default: 1MB
+	BatchSize int
+	// How often to send a batch that is not full.  Default: 5s
+	FlushInterval time.Duration
+
+	// Compress requests with gzip
+	Gzip bool
+
+	// Channel is the X-Splunk-Request-Channel GUID sent with every request.
+	// If empty and UseAck is set, a random channel is generated.
+	Channel string
+	// Wait for indexer acknowledgement of each batch, and redeliver batches that are not acknowledged
+	// within AckTimeout.  The HEC token must have indexer acknowledgement enabled.
+	UseAck bool
+	// How often to poll for acknowledgements.  Default: 1s
+	AckPollInterval time.Duration
+	// How long to wait for a batch to be acknowledged before redelivering it.  Default: 1m
+	AckTimeout time.Duration
+	// How many times to redeliver a batch before giving up on it.  Default: 3
+	// With a SpoolDir the batch is kept on disk and replayed later, otherwise it is dropped and passed to the ErrorHandler.
+	AckMaxRedeliveries int
+
+	// SpoolDir is a directory to write each batch to before it is sent.  If set, batches that fail to send
+	// or are not acknowledged are kept on disk and retried every SpoolRetryInterval instead of being dropped,
+	// including batches left over from a previous run.  Add, Flush and Send then return once the batch is on disk, and it is
+	// sent in the background with errors passed to the ErrorHandler.
+	// If UseAck is set but splunk returns no ack ID, the batch is kept on disk for the next run.
+	SpoolDir string
+	// Maximum total size in bytes of the spooled batches.  The oldest batches are dropped when it is full.  Default: 1GB
+	SpoolMaxBytes int64
+	// How often to retry sending spooled batches.  Default: 5s
+	SpoolRetryInterval time.Duration
+
+	// Called with errors from batches sent in the background.  If nil, the errors are dropped
+	ErrorHandler func(err error)
+}
+
+// HECEvent is a single event to send to splunk.
+//
+// Empty metadata fields use the defaults of the HEC token
+type HECEvent struct {
+	Time       time.Time
+	Index      string
+	Source     string
+	SourceType string
+	Host       string
+	// Indexed fields
+	Fields map[string]interface{}
+	// The event itself, a string or anything that can be marshaled to JSON
+	Event interface{}
+}
+
+// HECMetadata is the metadata of raw data sent to splunk.
+//
+// Empty fields use the defaults of the HEC token
+type HECMetadata struct {
+	Index      string
+	Source     string
+	SourceType string
+	Host       string
+}
+
+// hecEvent is the JSON splunk expects for an event
+type hecEvent struct {
+	Time       *float64               `json:"time,omitempty"`
+	Index      string                 `json:"index,omitempty"`
+	Source     string                 `json:"source,omitempty"`
+	SourceType string                 `json:"sourcetype,omitempty"`
+	Host       string                 `json:"host,omitempty"`
+	Fields     map[string]interface{} `json:"fields,omitempty"`
+	Event      interface{}            `json:"event"`
+}
+
+// MarshalJSON Converts the event to the JSON splunk expects
+func (e *HECEvent) MarshalJSON() ([]byte, error) {
+	event := hecEvent{
+		Index:      e.Index,
+		Source:     e.Source,
+		SourceType: e.SourceType,
+		Host:       e.Host,
+		Fields:     e.Fields,
+		Event:      e.Event,
+	}
+	if !e.Time.IsZero() {
+		epoch := float64(e.Time.UnixNano()) / float64(time.Second)
+		event.Time = &epoch
+	}
+	return json.Marshal(event)
+}
+
+// hecResponse is what splunk returns after sending events
+type hecResponse struct {
+	Text  string `json:"text"`
+	Code  int    `json:"code"`
+	AckID *int64 `json:"ackId"`
+}
+
+// errHECNoAckID is returned when UseAck is set but splunk did not return an ack ID
+var errHECNoAckID = errors.New("no ack ID returned, make sure indexer acknowledgement is enabled on the token")
+
+// hecBatch is a group of JSON encoded events sent in a single request
+type hecBatch struct {
+	payload bytes.Buffer
+	count   int
+	// Name of the spool file of this batch, if it is spooled
+	spoolName string
+}
+
+// NewHECClient Creates a new HEC client and starts flushing batches in the background
+func NewHECClient(config *HECConfig) (*HECClient, error) {
+	configCopy := *config
+	c := &HECClient{
+		config:  &configCopy,
+		batch:   &hecBatch{},
+		pending: map[uint64]*pendingBatch{},
+		done:    make(chan struct{}),
+		spooled: make(chan struct{}, 1),
+	}
+	if c.config.BaseURL == "" {
+		return nil, fmt.Errorf("no base URL provided")
+	}
+	if c.config.Token == "" {
+		return nil, fmt.Errorf("no token provided")
+	}
+	if c.config.HTTPClient == nil {
+		c.config.HTTPClient = http.DefaultClient
+	}
+	if c.config.BatchCount <= 0 {
+		c.config.BatchCount = defaultHECBatchCount
+	}
+	if c.config.BatchSize <= 0 {
+		c.config.BatchSize = defaultHECBatchSize
+	}
+	if c.config.FlushInterval <= 0 {
+		c.config.FlushInterval = defaultHECFlushInterval
+	}
+	if c.config.UseAck {
+		if c.config.Channel == "" {
+			channel, err := newChannel()
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate channel: %s", err)
+			}
+			c.config.Channel = channel
+		}
+		if c.config.AckPollInterval <= 0 {
+			c.config.AckPollInterval = defaultHECAckPollInterval
+		}
+		if c.config.AckTimeout <= 0 {
+			c.config.AckTimeout = defaultHECAckTimeout
+		}
+		if c.config.AckMaxRedeliveries <= 0 {
+			c.config.AckMaxRedeliveries = defaultHECAckMaxRedeliveries
+		}
+
+		c.wg.Add(1)
+		go c.ackLoop()
+	}
+	if c.config.SpoolDir != "" {
+		if c.config.SpoolMaxBytes <= 0 {
+			c.config.SpoolMaxBytes = defaultHECSpoolMaxBytes
+		}
+		if c.config.SpoolRetryInterval <= 0 {
+			c.config.SpoolRetryInterval = defaultHECSpoolRetryInterval
+		}
+		spool, err := openSpool(c.config.SpoolDir, c.config.SpoolMaxBytes)
+		if err != nil {
+			close(c.done)
+			c.wg.Wait()
+			return nil, fmt.Errorf("failed to open spool: %s", err)
+		}
+		c.spool = spool
+
+		var ctx context.Context
+		ctx, c.cancelSpool = context.WithCancel(context.Background())
+		c.wg.Add(1)
+		go c.spoolLoop(ctx)
+	}
+
+	c.wg.Add(1)
+	go c.flushLoop()
+
+	return c, nil
+}
+
+// flushLoop flushes the current batch every FlushInterval until the client is closed
+func (c *HECClient) flushLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.Flush(context.Background()); err != nil {
+				c.handleError(err)
+			}
+		}
+	}
+}
+
+// handleError passes a background error to the ErrorHandler if there is one
+func (c *HECClient) handleError(err error) {
+	if c.config.ErrorHandler != nil {
+		c.config.ErrorHandler(err)
+	}
+}
+
+// Add an event to the current batch.
+//
+// If the batch is full it is sent before returning, and any error sending it is returned.
+func (c *HECClient) Add(ctx context.Context, event *HECEvent) error {
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %s", err)
+	}
+
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return fmt.Errorf("client is closed")
+	}
+
+	// Send the current batch first if this event would make it too big
+	var full []*hecBatch
+	if c.batch.count > 0 && c.batch.payload.Len()+len(encoded) > c.config.BatchSize {
+		full = append(full, c.takeBatch())
+	}
+	c.batch.payload.Write(encoded)
+	c.batch.count++
+	if c.batch.count >= c.config.BatchCount || c.batch.payload.Len() >= c.config.BatchSize {
+		full = append(full, c.takeBatch())
+	}
+	c.lock.Unlock()
+
+	for _, batch := range full {
+		if err := c.sendBatch(ctx, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// takeBatch returns the current batch and starts a new one.  The lock must be held
+func (c *HECClient) takeBatch() *hecBatch {
+	batch := c.batch
+	c.batch = &hecBatch{}
+	return batch
+}
+
+// Flush sends the current batch now
+func (c *HECClient) Flush(ctx context.Context) error {
+	c.lock.Lock()
+	batch := c.takeBatch()
+	c.lock.Unlock()
+
+	if batch.count == 0 {
+		return nil
+	}
+	return c.sendBatch(ctx, batch)
+}
+
+// Close stops the background flushing and sends any remaining events.
+// If UseAck is set, it also waits for every batch to be acknowledged or until the context is done.
+// Events can not be added after the client is closed.
+func (c *HECClient) Close(ctx context.Context) error {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.lock.Unlock()
+
+	close(c.done)
+	if c.cancelSpool != nil {
+		// Let a batch being sent in the background finish, unless the context is done first
+		stopped := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				c.cancelSpool()
+			case <-stopped:
+			}
+		}()
+		c.wg.Wait()
+		close(stopped)
+		c.cancelSpool()
+	} else {
+		c.wg.Wait()
+	}
+
+	if err := c.Flush(ctx); err != nil {
+		return err
+	}
+	if c.spool != nil {
+		// Anything that is still not delivered stays in the spool for the next run
+		if err := c.replaySpool(ctx); err != nil {
+			c.handleError(err)
+		}
+	}
+	if c.config.UseAck {
+		return c.waitForAcks(ctx)
+	}
+	return nil
+}
+
+// Send events immediately in a single request, without batching
+func (c *HECClient) Send(ctx context.Context, events ...*HECEvent) error {
+	batch := &hecBatch{}
+	for _, event := range events {
+		encoded, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %s", err)
+		}
+		batch.payload.Write(encoded)
+		batch.count++
+	}
+	if batch.count == 0 {
+		return nil
+	}
+	return c.sendBatch(ctx, batch)
+}
+
+// SendRaw sends raw data immediately to the raw endpoint.  Splunk will break it into events using the sourcetype
+func (c *HECClient) SendRaw(ctx context.Context, data []byte, metadata *HECMetadata) error {
+	params := url.Values{}
+	if metadata != nil {
+		for key, value := range map[string]string{
+			"index":      metadata.Index,
+			"source":     metadata.Source,
+			"sourcetype": metadata.SourceType,
+			"host":       metadata.Host,
+		} {
+			if value != "" {
+				params.Add(key, value)
+			}
+		}
+	}
+
+	suffix := hecRawSuffix
+	if len(params) > 0 {
+		suffix = fmt.Sprintf("%s?%s", suffix, params.Encode())
+	}
+	return c.post(ctx, suffix, data, &hecResponse{})
+}
+
+// sendBatch sends a batch of events to the event endpoint, or writes it to the spool to send in the background if there is one
+func (c *HECClient) sendBatch(ctx context.Context, batch *hecBatch) error {
+	if c.spool == nil {
+		return c.deliverBatch(ctx, batch, 0)
+	}
+
+	dropped, err := c.spool.write(batch)
+	if dropped > 0 {
+		c.handleError(fmt.Errorf("spool is full, dropped %d events", dropped))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to spool %d events: %s", batch.count, err)
+	}
+	// It is safe on disk, let the spool loop send it so a slow or down splunk does not block the caller
+	c.spool.release(batch)
+	select {
+	case c.spooled <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// deliverBatch sends a batch of events to the event endpoint and tracks its ack ID if UseAck is set.
+// Attempt is the number of times the batch has been redelivered
+func (c *HECClient) deliverBatch(ctx context.Context, batch *hecBatch, attempt int) error {
+	result := &hecResponse{}
+	err := c.post(ctx, hecEventSuffix, batch.payload.Bytes(), result)
+	if err != nil {
+		return fmt.Errorf("failed to send %d events: %s", batch.count, err)
+	}
+	if c.config.UseAck {
+		if result.AckID == nil {
+			return fmt.Errorf("failed to track %d events: %w", batch.count, errHECNoAckID)
+		}
+		c.addPending(&pendingBatch{batch: batch, ackID: result.AckID, sent: time.Now(), attempt: attempt})
+		return nil
+	}
+	return c.removeFromSpool(batch)
+}
+
+// removeFromSpool deletes a delivered batch from the spool if it is spooled
+func (c *HECClient) removeFromSpool(batch *hecBatch) error {
+	if c.spool == nil || batch.spoolName == "" {
+		return nil
+	}
+	if err := c.spool.remove(batch); err != nil {
+		return fmt.Errorf("failed to remove delivered batch from spool: %s", err)
+	}
+	return nil
+}
+
+// post sends the payload to the HEC endpoint, compressing it if configured, and decodes the response into result
+func (c *HECClient) post(ctx context.Context, suffix string, payload []byte, result interface{}) error {
+	var body io.Reader = bytes.NewReader(payload)
+	if c.config.Gzip {
+		compressed := &bytes.Buffer{}
+		writer := gzip.NewWriter(compressed)
+		if _, err := writer.Write(payload); err != nil {
+			return err
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+		body = compressed
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.BaseURL+suffix, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Splunk %s", c.config.Token))
+	req.Header.Add("Content-Type", "application/json")
+	if c.config.Gzip {
+		req.Header.Add("Content-Encoding", "gzip")
+	}
+	if c.config.Channel != "" {
+		req.Header.Add("X-Splunk-Request-Channel", c.config.Channel)
+	}
+
+	resp, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("bad status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package splunk
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	hecAckSuffix = "/services/collector/ack"
+
+	defaultHECAckPollInterval    = time.Second
+	defaultHECAckTimeout         = time.Minute
+	defaultHECAckMaxRedeliveries = 3
+)
+
+// pendingBatch is a batch that was sent and is waiting to be acknowledged
+type pendingBatch struct {
+	batch *hecBatch
+	// The ack ID splunk returned for the batch, nil if it has none to poll and is redelivered once it expires
+	ackID   *int64
+	sent    time.Time
+	attempt int
+}
+
+// hecAckRequest is the body of an ack request
+type hecAckRequest struct {
+	Acks []int64 `json:"acks"`
+}
+
+// hecAckResponse is what splunk returns from an ack request
+type hecAckResponse struct {
+	Acks map[string]bool `json:"acks"`
+}
+
+// newChannel Generates a random UUID to use as a channel
+func newChannel() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	// Set version 4 and the variant bits
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// Channel Returns the channel used for requests, or "" if there is none
+func (c *HECClient) Channel() string {
+	return c.config.Channel
+}
+
+// Pending Returns the number of batches waiting to be acknowledged
+func (c *HECClient) Pending() int {
+	c.ackLock.Lock()
+	defer c.ackLock.Unlock()
+	return len(c.pending)
+}
+
+// addPending Tracks a sent batch until it is acknowledged.
+//
+// If splunk reused the ack ID of another pending batch, such as after a restart, the ack of that batch
+// can no longer be told apart, so it is redelivered on the next check.
+func (c *HECClient) addPending(pending *pendingBatch) {
+	c.ackLock.Lock()
+	defer c.ackLock.Unlock()
+	if pending.ackID != nil {
+		for _, other := range c.pending {
+			if other.ackID != nil && *other.ackID == *pending.ackID {
+				other.ackID = nil
+				other.sent = time.Time{}
+			}
+		}
+	}
+	c.pending[c.nextPendingID] = pending
+	c.nextPendingID++
+}
+
+// ackLoop polls for acknowledgements every AckPollInterval until the client is closed
+func (c *HECClient) ackLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.config.AckPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.checkAcks(context.Background()); err != nil {
+				c.handleError(err)
+			}
+		}
+	}
+}
+
+// waitForAcks polls for acknowledgements until there are no pending batches or the context is done
+func (c *HECClient) waitForAcks(ctx context.Context) error {
+	for {
+		if err := c.checkAcks(ctx); err != nil {
+			return err
+		}
+		if c.Pending() == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d batches not acknowledged: %s", c.Pending(), ctx.Err())
+		case <-time.After(c.config.AckPollInterval):
+		}
+	}
+}
+
+// checkAcks asks splunk which pending batches are acknowledged, and redelivers
+// the batches that have not been acknowledged within AckTimeout
+func (c *HECClient) checkAcks(ctx context.Context) error {
+	c.ackLock.Lock()
+	if len(c.pending) == 0 {
+		c.ackLock.Unlock()
+		return nil
+	}
+	// The ack ID of each polled batch by its local ID
+	polled := make(map[uint64]int64, len(c.pending))
+	request := hecAckRequest{Acks: make([]int64, 0, len(c.pending))}
+	for id, pending := range c.pending {
+		if pending.ackID != nil {
+			polled[id] = *pending.ackID
+			request.Acks = append(request.Acks, *pending.ackID)
+		}
+	}
+	c.ackLock.Unlock()
+
+	result := hecAckResponse{}
+	if len(request.Acks) > 0 {
+		payload, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		if err := c.post(ctx, hecAckSuffix, payload, &result); err != nil {
+			return fmt.Errorf("failed to check acks: %s", err)
+		}
+	}
+
+	// Remove the acknowledged batches and find the expired ones.
+	// Batches sent or displaced while polling are left for the next check
+	acked := []*hecBatch{}
+	expired := []*pendingBatch{}
+	c.ackLock.Lock()
+	for id, pending := range c.pending {
+		ackID, wasPolled := polled[id]
+		if wasPolled && pending.ackID != nil && *pending.ackID == ackID && result.Acks[strconv.FormatInt(ackID, 10)] {
+			delete(c.pending, id)
+			acked = append(acked, pending.batch)
+		} else if time.Since(pending.sent) > c.config.AckTimeout {
+			delete(c.pending, id)
+			expired = append(expired, pending)
+		}
+	}
+	c.ackLock.Unlock()
+
+	for _, batch := range acked {
+		if err := c.removeFromSpool(batch); err != nil {
+			return err
+		}
+	}
+
+	// Redeliver the expired batches
+	var redeliverErr error
+	for _, pending := range expired {
+		if pending.attempt >= c.config.AckMaxRedeliveries {
+			if c.spool != nil && pending.batch.spoolName != "" {
+				// Leave it on disk to be replayed
+				c.spool.release(pending.batch)
+				c.handleError(fmt.Errorf("%d events not acknowledged after %d redeliveries, keeping them in the spool", pending.batch.count, pending.attempt))
+				continue
+			}
+			c.handleError(fmt.Errorf("dropping %d events not acknowledged after %d redeliveries", pending.batch.count, pending.attempt))
+			continue
+		}
+		if err := c.deliverBatch(ctx, pending.batch, pending.attempt+1); err != nil {
+			// Keep it pending without an ack ID so it is tried again after another timeout
+			c.addPending(&pendingBatch{batch: pending.batch, sent: time.Now(), attempt: pending.attempt + 1})
+			redeliverErr = err
+		}
+	}
+
+	return redeliverErr
+}
@@ -0,0 +1,162 @@
+package splunk
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewChannel(t *testing.T) {
+	channel, err := newChannel()
+	require.NoError(t, err)
+	require.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, channel)
+}
+
+func TestHECClient_Ack(t *testing.T) {
+	// Server that acknowledges every batch except the first delivery
+	var lock sync.Mutex
+	nextAckID := int64(0)
+	deliveries := map[string]int{}
+	acked := map[int64]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Splunk-Request-Channel") != "11111111-2222-3333-4444-555555555555" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		switch req.URL.Path {
+		case "/services/collector/event":
+			deliveries[string(b)]++
+			acked[nextAckID] = deliveries[string(b)] > 1
+			rw.Write([]byte(`{"text":"Success","code":0,"ackId":` + strconv.FormatInt(nextAckID, 10) + `}`))
+			nextAckID++
+		case "/services/collector/ack":
+			request := hecAckRequest{}
+			if err := json.Unmarshal(b, &request); err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			response := hecAckResponse{Acks: map[string]bool{}}
+			for _, ackID := range request.Acks {
+				response.Acks[strconv.FormatInt(ackID, 10)] = acked[ackID]
+			}
+			json.NewEncoder(rw).Encode(response)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewHECClient(&HECConfig{
+		BaseURL:         server.URL,
+		Token:           "token",
+		Channel:         "11111111-2222-3333-4444-555555555555",
+		UseAck:          true,
+		AckPollInterval: time.Millisecond * 10,
+		AckTimeout:      time.Millisecond * 50,
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.Send(context.Background(), &HECEvent{Event: "hello"}))
+	require.Equal(t, 1, client.Pending())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, client.Close(ctx))
+	require.Equal(t, 0, client.Pending())
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, map[string]int{`{"event":"hello"}`: 2}, deliveries)
+}
+
+func TestHECClient_AckNotEnabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer server.Close()
+
+	client, err := NewHECClient(&HECConfig{BaseURL: server.URL, Token: "token", UseAck: true})
+	require.NoError(t, err)
+	defer client.Close(context.Background())
+	require.NotEmpty(t, client.Channel())
+	require.Error(t, client.Send(context.Background(), &HECEvent{Event: "hello"}))
+}
+
+func TestHECClient_AckIDReused(t *testing.T) {
+	// Server that restarts after the first batch, so the second batch gets the same ack ID
+	var lock sync.Mutex
+	ackIDs := []int64{0, 0, 1}
+	deliveries := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		switch req.URL.Path {
+		case "/services/collector/event":
+			if len(ackIDs) == 0 {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			deliveries[string(b)]++
+			rw.Write([]byte(`{"text":"Success","code":0,"ackId":` + strconv.FormatInt(ackIDs[0], 10) + `}`))
+			ackIDs = ackIDs[1:]
+		case "/services/collector/ack":
+			request := hecAckRequest{}
+			if err := json.Unmarshal(b, &request); err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			response := hecAckResponse{Acks: map[string]bool{}}
+			for _, ackID := range request.Acks {
+				response.Acks[strconv.FormatInt(ackID, 10)] = true
+			}
+			json.NewEncoder(rw).Encode(response)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewHECClient(&HECConfig{
+		BaseURL:         server.URL,
+		Token:           "token",
+		UseAck:          true,
+		AckPollInterval: time.Hour,
+		AckTimeout:      time.Hour,
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.Send(context.Background(), &HECEvent{Event: "first"}))
+	require.NoError(t, client.Send(context.Background(), &HECEvent{Event: "second"}))
+	require.Equal(t, 2, client.Pending())
+
+	// The ack of ID 0 belongs to the second batch, the first is redelivered
+	require.NoError(t, client.checkAcks(context.Background()))
+	require.Equal(t, 1, client.Pending())
+	require.NoError(t, client.checkAcks(context.Background()))
+	require.Equal(t, 0, client.Pending())
+	require.NoError(t, client.Close(context.Background()))
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, map[string]int{`{"event":"first"}`: 2, `{"event":"second"}`: 1}, deliveries)
+}
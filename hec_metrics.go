@@ -0,0 +1,80 @@
+package splunk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	metricNamePrefix = "metric_name:"
+)
+
+// MetricPoint is a multi-metric data point to send to a splunk metrics index
+//
+// Empty metadata fields use the defaults of the HEC token
+type MetricPoint struct {
+	Time       time.Time
+	Index      string
+	Source     string
+	SourceType string
+	Host       string
+	// Metrics are the measurements by metric name, ex: "cpu.idle": 95.1
+	Metrics map[string]float64
+	// Dimensions describe the measurements, ex: "region": "us-west-1"
+	Dimensions map[string]string
+}
+
+// HECEvent Converts the metric point to the event splunk expects for metrics
+func (p *MetricPoint) HECEvent() (*HECEvent, error) {
+	if len(p.Metrics) == 0 {
+		return nil, fmt.Errorf("metric point has no metrics")
+	}
+
+	fields := make(map[string]interface{}, len(p.Metrics)+len(p.Dimensions))
+	for name, value := range p.Dimensions {
+		if strings.HasPrefix(name, metricNamePrefix) || name == "_value" {
+			return nil, fmt.Errorf("bad dimension name: %s", name)
+		}
+		fields[name] = value
+	}
+	for name, value := range p.Metrics {
+		if name == "" {
+			return nil, fmt.Errorf("metric name is empty")
+		}
+		fields[metricNamePrefix+name] = value
+	}
+
+	return &HECEvent{
+		Time:       p.Time,
+		Index:      p.Index,
+		Source:     p.Source,
+		SourceType: p.SourceType,
+		Host:       p.Host,
+		Fields:     fields,
+		Event:      "metric",
+	}, nil
+}
+
+// AddMetric adds a metric point to the current batch, see Add
+func (c *HECClient) AddMetric(ctx context.Context, point *MetricPoint) error {
+	event, err := point.HECEvent()
+	if err != nil {
+		return err
+	}
+	return c.Add(ctx, event)
+}
+
+// SendMetrics sends metric points immediately in a single request, without batching
+func (c *HECClient) SendMetrics(ctx context.Context, points ...*MetricPoint) error {
+	events := make([]*HECEvent, 0, len(points))
+	for _, point := range points {
+		event, err := point.HECEvent()
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+	return c.Send(ctx, events...)
+}
@@ -0,0 +1,56 @@
+package splunk
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricPoint_HECEvent(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		point := &MetricPoint{
+			Time:       time.Unix(1600000000, 0),
+			Index:      "metrics",
+			Host:       "web01",
+			Metrics:    map[string]float64{"cpu.idle": 95.5, "mem.free": 1024},
+			Dimensions: map[string]string{"region": "us-west-1"},
+		}
+		event, err := point.HECEvent()
+		require.NoError(t, err)
+		b, err := json.Marshal(event)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"time":1600000000,"index":"metrics","host":"web01","event":"metric","fields":{"region":"us-west-1","metric_name:cpu.idle":95.5,"metric_name:mem.free":1024}}`, string(b))
+	})
+
+	t.Run("no metrics", func(t *testing.T) {
+		_, err := (&MetricPoint{Dimensions: map[string]string{"region": "us-west-1"}}).HECEvent()
+		require.Error(t, err)
+	})
+
+	t.Run("bad dimension", func(t *testing.T) {
+		_, err := (&MetricPoint{
+			Metrics:    map[string]float64{"cpu.idle": 95.5},
+			Dimensions: map[string]string{"metric_name:cpu.idle": "1"},
+		}).HECEvent()
+		require.Error(t, err)
+	})
+}
+
+func TestHECClient_AddMetric(t *testing.T) {
+	server := newHECTestServer(t)
+	defer server.Close()
+
+	client, err := NewHECClient(&HECConfig{BaseURL: server.URL, Token: "token", BatchCount: 2, FlushInterval: time.Hour})
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, client.AddMetric(context.Background(), &MetricPoint{Metrics: map[string]float64{"requests": float64(i)}}))
+	}
+	require.NoError(t, client.Close(context.Background()))
+	require.Len(t, server.requests, 1)
+	require.Len(t, server.requests[0], 2)
+	require.Equal(t, "metric", server.requests[0][1]["event"])
+	require.Equal(t, map[string]interface{}{"metric_name:requests": float64(1)}, server.requests[0][1]["fields"])
+}
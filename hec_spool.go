@@ -0,0 +1,292 @@
+package splunk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	spoolFileSuffix = ".batch"
+	spoolTempSuffix = ".tmp"
+	spoolBadSuffix  = ".bad"
+
+	defaultHECSpoolMaxBytes      = 1024 * 1024 * 1024
+	defaultHECSpoolRetryInterval = time.Second * 5
+)
+
+// hecSpool is an on-disk write-ahead log of batches.
+//
+// Each batch is written to its own file named <sequence>-<count>.batch before it is sent, and removed once
+// it is delivered (or acknowledged).  Files left behind by a crash are replayed when the spool is opened again.
+type hecSpool struct {
+	dir      string
+	maxBytes int64
+
+	lock sync.Mutex
+	// Total size of the spooled batches
+	size int64
+	// Sequence number of the next batch
+	sequence uint64
+	// Size of each spooled batch by file name
+	files map[string]int64
+	// Batches currently being sent or waiting on an ack, these are not replayed
+	inFlight map[string]bool
+}
+
+// openSpool Opens the spool in dir, creating it if needed, and loads any batches left from a previous run
+func openSpool(dir string, maxBytes int64) (*hecSpool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	s := &hecSpool{
+		dir:      dir,
+		maxBytes: maxBytes,
+		files:    map[string]int64{},
+		inFlight: map[string]bool{},
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		name := info.Name()
+		switch {
+		case strings.HasSuffix(name, spoolTempSuffix):
+			// A batch that was never fully written, it was never sent either
+			os.Remove(filepath.Join(dir, name))
+		case strings.HasSuffix(name, spoolFileSuffix):
+			sequence, _, err := parseSpoolName(name)
+			if err != nil {
+				continue
+			}
+			s.files[name] = info.Size()
+			s.size += info.Size()
+			if sequence >= s.sequence {
+				s.sequence = sequence + 1
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// parseSpoolName Gets the sequence number and event count from a spool file name
+func parseSpoolName(name string) (uint64, int, error) {
+	parts := strings.SplitN(strings.TrimSuffix(name, spoolFileSuffix), "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("bad spool file name: %s", name)
+	}
+	sequence, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad spool file name: %s", name)
+	}
+	count, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad spool file name: %s", name)
+	}
+	return sequence, count, nil
+}
+
+// write Saves the batch to disk and marks it in flight.
+//
+// If the spool is full, the oldest batches that are not in flight are dropped to make room.
+// It returns the number of events dropped.
+func (s *hecSpool) write(batch *hecBatch) (int, error) {
+	size := int64(batch.payload.Len())
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if size > s.maxBytes {
+		return 0, fmt.Errorf("batch of %d bytes is larger than the spool", size)
+	}
+
+	// Make room
+	dropped := 0
+	if s.size+size > s.maxBytes {
+		for _, name := range s.sortedNames() {
+			if s.size+size <= s.maxBytes {
+				break
+			}
+			if s.inFlight[name] {
+				continue
+			}
+			_, count, _ := parseSpoolName(name)
+			if err := s.removeLocked(name); err != nil {
+				return dropped, err
+			}
+			dropped += count
+		}
+		if s.size+size > s.maxBytes {
+			return dropped, fmt.Errorf("spool is full")
+		}
+	}
+
+	// Write to a temp file and rename it so a crash never leaves a partial batch
+	name := fmt.Sprintf("%020d-%d%s", s.sequence, batch.count, spoolFileSuffix)
+	temp := filepath.Join(s.dir, name+spoolTempSuffix)
+	file, err := os.OpenFile(temp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return dropped, err
+	}
+	if _, err := file.Write(batch.payload.Bytes()); err != nil {
+		file.Close()
+		os.Remove(temp)
+		return dropped, err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(temp)
+		return dropped, err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(temp)
+		return dropped, err
+	}
+	if err := os.Rename(temp, filepath.Join(s.dir, name)); err != nil {
+		os.Remove(temp)
+		return dropped, err
+	}
+
+	s.sequence++
+	s.files[name] = size
+	s.size += size
+	s.inFlight[name] = true
+	batch.spoolName = name
+
+	return dropped, nil
+}
+
+// claim Marks the oldest batch that is not in flight as in flight and reads it.  It returns nil if there are none.
+//
+// A batch whose file is gone is forgotten, and one that can not be read is renamed to <name>.bad, so neither
+// blocks the batches after it.  Both are passed to handleError
+func (s *hecSpool) claim(handleError func(err error)) *hecBatch {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, name := range s.sortedNames() {
+		if s.inFlight[name] {
+			continue
+		}
+		_, count, _ := parseSpoolName(name)
+		path := filepath.Join(s.dir, name)
+		payload, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			s.forgetLocked(name)
+			handleError(fmt.Errorf("spooled batch %s is missing, dropped %d events", name, count))
+			continue
+		}
+		if err != nil {
+			// If the rename fails too, it is retried when the spool is opened again
+			os.Rename(path, path+spoolBadSuffix)
+			s.forgetLocked(name)
+			handleError(fmt.Errorf("failed to read spooled batch %s, moved it aside: %s", name, err))
+			continue
+		}
+		batch := &hecBatch{count: count, spoolName: name}
+		batch.payload.Write(payload)
+		s.inFlight[name] = true
+		return batch
+	}
+	return nil
+}
+
+// release Marks the batch as not in flight so it is replayed
+func (s *hecSpool) release(batch *hecBatch) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.inFlight, batch.spoolName)
+}
+
+// remove Deletes the batch from the spool once it is delivered
+func (s *hecSpool) remove(batch *hecBatch) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.removeLocked(batch.spoolName)
+}
+
+// removeLocked Deletes the spool file.  The lock must be held
+func (s *hecSpool) removeLocked(name string) error {
+	if _, ok := s.files[name]; !ok {
+		return nil
+	}
+	if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	s.forgetLocked(name)
+	return nil
+}
+
+// forgetLocked Stops tracking the spool file without touching the disk.  The lock must be held
+func (s *hecSpool) forgetLocked(name string) {
+	s.size -= s.files[name]
+	delete(s.files, name)
+	delete(s.inFlight, name)
+}
+
+// sortedNames Returns the spool file names oldest first.  The lock must be held
+func (s *hecSpool) sortedNames() []string {
+	names := make([]string, 0, len(s.files))
+	for name := range s.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Spooled Returns the number of batches in the spool, including those in flight.  It is 0 if there is no spool
+func (c *HECClient) Spooled() int {
+	if c.spool == nil {
+		return 0
+	}
+	c.spool.lock.Lock()
+	defer c.spool.lock.Unlock()
+	return len(c.spool.files)
+}
+
+// spoolLoop sends spooled batches as they are written, and retries them every SpoolRetryInterval, until the client is closed.
+// A send in progress is only stopped by cancelling ctx, which Close does if its own context is done first
+func (c *HECClient) spoolLoop(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.config.SpoolRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		case <-c.spooled:
+		}
+		if err := c.replaySpool(ctx); err != nil && ctx.Err() == nil {
+			c.handleError(err)
+		}
+	}
+}
+
+// replaySpool sends the spooled batches oldest first, stopping at the first failure.
+// A batch that gets no ack ID stays claimed so it is not replayed again until the next run
+func (c *HECClient) replaySpool(ctx context.Context) error {
+	for {
+		batch := c.spool.claim(c.handleError)
+		if batch == nil {
+			return nil
+		}
+		if err := c.deliverBatch(ctx, batch, 0); err != nil {
+			if !errors.Is(err, errHECNoAckID) {
+				c.spool.release(batch)
+			}
+			return err
+		}
+	}
+}
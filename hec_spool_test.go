@@ -0,0 +1,283 @@
+package splunk
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHECSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	spool, err := openSpool(dir, 40)
+	require.NoError(t, err)
+
+	write := func(payload string, count int) *hecBatch {
+		batch := &hecBatch{count: count}
+		batch.payload.WriteString(payload)
+		dropped, err := spool.write(batch)
+		require.NoError(t, err)
+		require.Equal(t, 0, dropped)
+		spool.release(batch)
+		return batch
+	}
+	write(`{"event":"1"}`, 1)
+	second := write(`{"event":"2"}`, 1)
+	require.Equal(t, int64(26), spool.size)
+
+	// A partially written batch from a crash is removed
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "00000000000000000009-1.batch.tmp"), []byte("{"), 0600))
+
+	// Reopening resumes from disk
+	spool, err = openSpool(dir, 40)
+	require.NoError(t, err)
+	require.Equal(t, int64(26), spool.size)
+	require.Equal(t, uint64(2), spool.sequence)
+	_, err = os.Stat(filepath.Join(dir, "00000000000000000009-1.batch.tmp"))
+	require.True(t, os.IsNotExist(err))
+
+	// Claimed oldest first
+	batch := spool.claim(func(err error) { require.NoError(t, err) })
+	require.Equal(t, `{"event":"1"}`, batch.payload.String())
+	require.Equal(t, 1, batch.count)
+
+	// Full spool drops the oldest batch that is not in flight
+	third := &hecBatch{count: 3}
+	third.payload.WriteString(`{"event":"3"}{"event":"3"}`)
+	dropped, err := spool.write(third)
+	require.NoError(t, err)
+	require.Equal(t, 1, dropped)
+	_, err = os.Stat(filepath.Join(dir, second.spoolName))
+	require.True(t, os.IsNotExist(err))
+
+	require.NoError(t, spool.remove(batch))
+	require.NoError(t, spool.remove(third))
+	require.Equal(t, int64(0), spool.size)
+}
+
+func TestHECClient_Spool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Server that is down until up is set
+	var lock sync.Mutex
+	up := false
+	received := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if !up {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		b, _ := ioutil.ReadAll(req.Body)
+		received = append(received, string(b))
+		rw.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer server.Close()
+
+	errs := make(chan error, 10)
+	config := &HECConfig{
+		BaseURL:            server.URL,
+		Token:              "token",
+		SpoolDir:           dir,
+		SpoolRetryInterval: time.Hour,
+		ErrorHandler:       func(err error) { errs <- err },
+	}
+	client, err := NewHECClient(config)
+	require.NoError(t, err)
+
+	// Sending while splunk is down keeps the batch on disk
+	require.NoError(t, client.Send(context.Background(), &HECEvent{Event: "first"}))
+	require.Error(t, <-errs)
+	require.Equal(t, 1, client.Spooled())
+	require.NoError(t, client.Close(context.Background()))
+	require.Error(t, <-errs)
+
+	// A new client picks up the spooled batch and replays it once splunk is back
+	lock.Lock()
+	up = true
+	lock.Unlock()
+	config.SpoolRetryInterval = time.Millisecond * 10
+	client, err = NewHECClient(config)
+	require.NoError(t, err)
+	require.Equal(t, 1, client.Spooled())
+	require.Eventually(t, func() bool {
+		return client.Spooled() == 0
+	}, time.Second, time.Millisecond*10)
+	require.NoError(t, client.Send(context.Background(), &HECEvent{Event: "second"}))
+	require.NoError(t, client.Close(context.Background()))
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, []string{`{"event":"first"}`, `{"event":"second"}`}, received)
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 0)
+}
+
+func TestHECClient_SpoolUnacknowledged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Server that accepts every batch but never acknowledges one
+	var lock sync.Mutex
+	deliveries := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if req.URL.Path == "/services/collector/ack" {
+			rw.Write([]byte(`{"acks":{}}`))
+			return
+		}
+		deliveries++
+		rw.Write([]byte(`{"text":"Success","code":0,"ackId":` + strconv.Itoa(deliveries) + `}`))
+	}))
+	defer server.Close()
+
+	errs := make(chan error, 10)
+	client, err := NewHECClient(&HECConfig{
+		BaseURL:            server.URL,
+		Token:              "token",
+		UseAck:             true,
+		AckPollInterval:    time.Hour,
+		AckTimeout:         time.Nanosecond,
+		AckMaxRedeliveries: 1,
+		SpoolDir:           dir,
+		SpoolRetryInterval: time.Hour,
+		ErrorHandler:       func(err error) { errs <- err },
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.Send(context.Background(), &HECEvent{Event: "hello"}))
+	require.Eventually(t, func() bool {
+		return client.Pending() == 1
+	}, time.Second, time.Millisecond*10)
+
+	// Redelivered once, then given up on but kept on disk
+	require.NoError(t, client.checkAcks(context.Background()))
+	require.NoError(t, client.checkAcks(context.Background()))
+	require.Contains(t, (<-errs).Error(), "keeping them in the spool")
+	require.Equal(t, 0, client.Pending())
+	require.Equal(t, 1, client.Spooled())
+
+	// Closing replays it, and it stays on disk when it is still not acknowledged
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	require.Error(t, client.Close(ctx))
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
+
+func TestHECClient_SpoolNoAckID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Server with indexer acknowledgement disabled
+	var lock sync.Mutex
+	deliveries := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		deliveries++
+		rw.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer server.Close()
+
+	errs := make(chan error, 10)
+	client, err := NewHECClient(&HECConfig{
+		BaseURL:            server.URL,
+		Token:              "token",
+		UseAck:             true,
+		SpoolDir:           dir,
+		SpoolRetryInterval: time.Hour,
+		ErrorHandler:       func(err error) { errs <- err },
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.Send(context.Background(), &HECEvent{Event: "hello"}))
+	require.True(t, errors.Is(<-errs, errHECNoAckID))
+	require.Equal(t, 1, client.Spooled())
+
+	// It is not replayed again in this run, but stays on disk for the next
+	require.NoError(t, client.Close(context.Background()))
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, 1, deliveries)
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
+
+func TestHECSpool_ClaimUnreadable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// A batch deleted from under the spool, one that can not be read, then a good one
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "00000000000000000000-1.batch"), []byte(`{"event":"1"}`), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "00000000000000000001-1.batch"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "00000000000000000002-1.batch"), []byte(`{"event":"3"}`), 0600))
+	spool, err := openSpool(dir, 1024)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(dir, "00000000000000000000-1.batch")))
+
+	errs := []error{}
+	batch := spool.claim(func(err error) { errs = append(errs, err) })
+	require.NotNil(t, batch)
+	require.Equal(t, `{"event":"3"}`, batch.payload.String())
+	require.Len(t, errs, 2)
+	require.Contains(t, errs[0].Error(), "missing")
+	require.Contains(t, errs[1].Error(), "moved it aside")
+	_, err = os.Stat(filepath.Join(dir, "00000000000000000001-1.batch.bad"))
+	require.NoError(t, err)
+
+	require.NoError(t, spool.remove(batch))
+	require.Len(t, spool.files, 0)
+	require.Equal(t, int64(0), spool.size)
+}
+
+func TestHECClient_SpoolDoesNotBlock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Server that hangs until unblocked
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		<-unblock
+		rw.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer server.Close()
+
+	client, err := NewHECClient(&HECConfig{
+		BaseURL:            server.URL,
+		Token:              "token",
+		SpoolDir:           dir,
+		SpoolRetryInterval: time.Hour,
+	})
+	require.NoError(t, err)
+
+	// Send returns once the batch is on disk
+	require.NoError(t, client.Send(context.Background(), &HECEvent{Event: "hello"}))
+	require.Equal(t, 1, client.Spooled())
+
+	close(unblock)
+	require.NoError(t, client.Close(context.Background()))
+	require.Equal(t, 0, client.Spooled())
+}
@@ -0,0 +1,170 @@
+package splunk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// hecTestServer records the events sent to it
+type hecTestServer struct {
+	*httptest.Server
+	lock     sync.Mutex
+	requests [][]map[string]interface{}
+}
+
+func newHECTestServer(t *testing.T) *hecTestServer {
+	s := &hecTestServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method != http.MethodPost:
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		case req.URL.Path != "/services/collector/event":
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		case req.Header.Get("Authorization") != "Splunk token":
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var body io.Reader = req.Body
+		if req.Header.Get("Content-Encoding") == "gzip" {
+			reader, err := gzip.NewReader(req.Body)
+			if err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = reader
+		}
+
+		events := []map[string]interface{}{}
+		decoder := json.NewDecoder(body)
+		for decoder.More() {
+			event := map[string]interface{}{}
+			if err := decoder.Decode(&event); err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			events = append(events, event)
+		}
+		s.lock.Lock()
+		s.requests = append(s.requests, events)
+		s.lock.Unlock()
+
+		rw.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	return s
+}
+
+func TestHECEvent_MarshalJSON(t *testing.T) {
+	event := &HECEvent{
+		Time:       time.Unix(1600000000, 500000000),
+		Index:      "main",
+		SourceType: "json",
+		Fields:     map[string]interface{}{"env": "prod"},
+		Event:      map[string]string{"message": "hello"},
+	}
+	b, err := json.Marshal(event)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"time":1600000000.5,"index":"main","sourcetype":"json","fields":{"env":"prod"},"event":{"message":"hello"}}`, string(b))
+}
+
+func TestHECClient_Add(t *testing.T) {
+	t.Run("batch count", func(t *testing.T) {
+		server := newHECTestServer(t)
+		defer server.Close()
+
+		client, err := NewHECClient(&HECConfig{BaseURL: server.URL, Token: "token", BatchCount: 2, FlushInterval: time.Hour})
+		require.NoError(t, err)
+		for i := 0; i < 5; i++ {
+			require.NoError(t, client.Add(context.Background(), &HECEvent{Event: i}))
+		}
+		require.Len(t, server.requests, 2)
+		require.NoError(t, client.Close(context.Background()))
+		require.Len(t, server.requests, 3)
+		require.Len(t, server.requests[2], 1)
+		require.Equal(t, float64(4), server.requests[2][0]["event"])
+
+		require.Error(t, client.Add(context.Background(), &HECEvent{Event: "closed"}))
+	})
+
+	t.Run("batch size", func(t *testing.T) {
+		server := newHECTestServer(t)
+		defer server.Close()
+
+		client, err := NewHECClient(&HECConfig{BaseURL: server.URL, Token: "token", BatchSize: 30, FlushInterval: time.Hour})
+		require.NoError(t, err)
+		// Each event is 20 bytes so only one fits in a batch
+		require.NoError(t, client.Add(context.Background(), &HECEvent{Event: "1234567"}))
+		require.NoError(t, client.Add(context.Background(), &HECEvent{Event: "1234567"}))
+		require.Len(t, server.requests, 1)
+		require.NoError(t, client.Close(context.Background()))
+		require.Len(t, server.requests, 2)
+	})
+
+	t.Run("interval", func(t *testing.T) {
+		server := newHECTestServer(t)
+		defer server.Close()
+
+		client, err := NewHECClient(&HECConfig{BaseURL: server.URL, Token: "token", FlushInterval: time.Millisecond * 10})
+		require.NoError(t, err)
+		defer client.Close(context.Background())
+		require.NoError(t, client.Add(context.Background(), &HECEvent{Event: "hello"}))
+		require.Eventually(t, func() bool {
+			server.lock.Lock()
+			defer server.lock.Unlock()
+			return len(server.requests) == 1
+		}, time.Second, time.Millisecond*10)
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		server := newHECTestServer(t)
+		defer server.Close()
+
+		client, err := NewHECClient(&HECConfig{BaseURL: server.URL, Token: "token", Gzip: true})
+		require.NoError(t, err)
+		require.NoError(t, client.Send(context.Background(), &HECEvent{Event: "a"}, &HECEvent{Event: "b"}))
+		require.NoError(t, client.Close(context.Background()))
+		require.Len(t, server.requests, 1)
+		require.Len(t, server.requests[0], 2)
+	})
+}
+
+func TestHECClient_SendRaw(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method != http.MethodPost:
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			case req.RequestURI != "/services/collector/raw?index=main&sourcetype=syslog":
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			b, _ := ioutil.ReadAll(req.Body)
+			if !bytes.Equal(b, []byte("line 1\nline 2\n")) {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			rw.Write([]byte(`{"text":"Success","code":0}`))
+		}))
+		defer server.Close()
+
+		client, err := NewHECClient(&HECConfig{BaseURL: server.URL, Token: "token"})
+		require.NoError(t, err)
+		defer client.Close(context.Background())
+		err = client.SendRaw(context.Background(), []byte("line 1\nline 2\n"), &HECMetadata{Index: "main", SourceType: "syslog"})
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,375 @@
+package splunk
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// CursorIndexTime tracks the cursor by when events were indexed.  Late events are still found
+	CursorIndexTime = "_indextime"
+	// CursorEventTime tracks the cursor by the time of the events
+	CursorEventTime = "_time"
+
+	// Field the index time is copied into, hidden fields are not returned otherwise
+	incrementalIndexTimeField = "incremental_indextime"
+
+	defaultIncrementalLag = time.Minute
+)
+
+// Checkpoint is the saved position of an incremental search
+type Checkpoint struct {
+	// Results up to this time have been searched
+	Cursor time.Time `json:"cursor"`
+	// Keys of the results already emitted that are within the lag of the cursor, with their cursor time
+	Seen map[string]time.Time `json:"seen"`
+}
+
+// CheckpointStore saves the checkpoints of incremental searches by key
+type CheckpointStore interface {
+	// Load Returns the checkpoint for the key, or nil if there is none
+	Load(ctx context.Context, key string) (*Checkpoint, error)
+	// Save Saves the checkpoint for the key
+	Save(ctx context.Context, key string, checkpoint *Checkpoint) error
+}
+
+// MemoryCheckpointStore keeps checkpoints in memory, they are lost when the program exits
+type MemoryCheckpointStore struct {
+	lock        sync.Mutex
+	checkpoints map[string]Checkpoint
+}
+
+// NewMemoryCheckpointStore Creates an empty in memory checkpoint store
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: map[string]Checkpoint{}}
+}
+
+// Load Returns the checkpoint for the key, or nil if there is none
+func (s *MemoryCheckpointStore) Load(ctx context.Context, key string) (*Checkpoint, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	checkpoint, ok := s.checkpoints[key]
+	if !ok {
+		return nil, nil
+	}
+	checkpoint.Seen = copySeen(checkpoint.Seen)
+	return &checkpoint, nil
+}
+
+// Save Saves the checkpoint for the key
+func (s *MemoryCheckpointStore) Save(ctx context.Context, key string, checkpoint *Checkpoint) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.checkpoints[key] = Checkpoint{Cursor: checkpoint.Cursor, Seen: copySeen(checkpoint.Seen)}
+	return nil
+}
+
+// FileCheckpointStore keeps each checkpoint in a JSON file in Dir
+type FileCheckpointStore struct {
+	Dir string
+}
+
+// path Returns the file of the key's checkpoint
+func (s *FileCheckpointStore) path(key string) string {
+	return filepath.Join(s.Dir, url.PathEscape(key)+".json")
+}
+
+// Load Returns the checkpoint for the key, or nil if there is none
+func (s *FileCheckpointStore) Load(ctx context.Context, key string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := &Checkpoint{}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("bad checkpoint %s: %s", s.path(key), err)
+	}
+	return checkpoint, nil
+}
+
+// Save Saves the checkpoint for the key.  It is written to a temp file and renamed so a crash never leaves a partial checkpoint
+func (s *FileCheckpointStore) Save(ctx context.Context, key string, checkpoint *Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+	temp := s.path(key) + ".tmp"
+	if err := ioutil.WriteFile(temp, data, 0600); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	if err := os.Rename(temp, s.path(key)); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	return nil
+}
+
+// copySeen Returns a copy of the seen keys
+func copySeen(seen map[string]time.Time) map[string]time.Time {
+	copied := make(map[string]time.Time, len(seen))
+	for key, cursor := range seen {
+		copied[key] = cursor
+	}
+	return copied
+}
+
+// IncrementalSearchOptions are the options of an incremental search
+type IncrementalSearchOptions struct {
+	// Key the checkpoint is saved under, unique to the search
+	Key string
+	// Where the checkpoint is saved.  Default: in memory
+	Store CheckpointStore
+	// Field the cursor tracks, CursorIndexTime or CursorEventTime.  Default: CursorIndexTime
+	CursorField string
+	// How far before the cursor each poll searches again, to find events that arrive late.
+	// Results found again are de-duplicated.  Default: 1 minute
+	Lag time.Duration
+	// Where to start if there is no checkpoint.  Default: the time of the first poll
+	Start time.Time
+	// Returns the key used to de-duplicate a result.  Default: the result's _cd and _bkt, or a hash of the whole result
+	ResultKey func(result SearchResult) string
+	// Params are any other parameters for each job, see CreateSearchJob
+	Params map[string]string
+}
+
+// IncrementalSearch runs the same query repeatedly, returning only the results that are new since the last run
+type IncrementalSearch struct {
+	client  *Client
+	query   string
+	options IncrementalSearchOptions
+
+	lock       sync.Mutex
+	checkpoint *Checkpoint
+	// Current time, replaced in tests
+	now func() time.Time
+}
+
+// NewIncrementalSearch Creates an incremental search of the query.  Nothing is searched until Poll or Run
+func (c *Client) NewIncrementalSearch(query string, options *IncrementalSearchOptions) (*IncrementalSearch, error) {
+	if options.Key == "" {
+		return nil, fmt.Errorf("a checkpoint key is required")
+	}
+	s := &IncrementalSearch{
+		client:  c,
+		query:   query,
+		options: *options,
+		now:     time.Now,
+	}
+	if s.options.Store == nil {
+		s.options.Store = NewMemoryCheckpointStore()
+	}
+	switch s.options.CursorField {
+	case "":
+		s.options.CursorField = CursorIndexTime
+	case CursorIndexTime, CursorEventTime:
+	default:
+		return nil, fmt.Errorf("cursor field must be %s or %s", CursorIndexTime, CursorEventTime)
+	}
+	if s.options.Lag <= 0 {
+		s.options.Lag = defaultIncrementalLag
+	}
+	if s.options.ResultKey == nil {
+		s.options.ResultKey = defaultResultKey
+	}
+	return s, nil
+}
+
+// Checkpoint Returns a copy of the current checkpoint, or nil if the search has not loaded or made one yet
+func (s *IncrementalSearch) Checkpoint() *Checkpoint {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.checkpoint == nil {
+		return nil
+	}
+	return &Checkpoint{Cursor: s.checkpoint.Cursor, Seen: copySeen(s.checkpoint.Seen)}
+}
+
+// Poll Searches once and returns the new results, oldest first.  The checkpoint is saved before returning,
+// so results are lost if the program exits before handling them.  Use Run to save only after they are handled.
+func (s *IncrementalSearch) Poll(ctx context.Context) ([]SearchResult, error) {
+	return s.poll(ctx, nil)
+}
+
+// Run Polls every interval and calls handler with the new results until the context is done or handler returns an error.
+// The checkpoint is saved after handler succeeds, so results are handled again after a failure.
+func (s *IncrementalSearch) Run(ctx context.Context, interval time.Duration, handler func(results []SearchResult) error) error {
+	if interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.poll(ctx, handler); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll Searches from the cursor, less the lag, up to now.  The new results are passed to handler (if any) before the checkpoint is saved
+func (s *IncrementalSearch) poll(ctx context.Context, handler func(results []SearchResult) error) ([]SearchResult, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.checkpoint == nil {
+		checkpoint, err := s.options.Store.Load(ctx, s.options.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load checkpoint: %s", err)
+		}
+		if checkpoint == nil {
+			start := s.options.Start
+			if start.IsZero() {
+				start = s.now()
+			}
+			checkpoint = &Checkpoint{Cursor: start}
+		}
+		if checkpoint.Seen == nil {
+			checkpoint.Seen = map[string]time.Time{}
+		}
+		s.checkpoint = checkpoint
+	}
+
+	earliest := s.checkpoint.Cursor.Add(-s.options.Lag)
+	latest := s.now()
+	results, err := s.search(ctx, earliest, latest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep only the results not emitted by an earlier poll
+	next := &Checkpoint{Cursor: latest, Seen: copySeen(s.checkpoint.Seen)}
+	newResults := []SearchResult{}
+	for _, result := range results {
+		key := s.options.ResultKey(result)
+		if _, ok := next.Seen[key]; ok {
+			continue
+		}
+		cursor, err := s.resultCursor(result)
+		if err != nil {
+			// Assume it is at the end of the range so it is remembered as long as possible
+			cursor = latest
+		}
+		next.Seen[key] = cursor
+		newResults = append(newResults, result)
+	}
+	if next.Cursor.Before(s.checkpoint.Cursor) {
+		// The clock went backwards, do not search the same range again
+		next.Cursor = s.checkpoint.Cursor
+	}
+
+	// Forget the results that the next poll can not find again
+	for key, cursor := range next.Seen {
+		if cursor.Before(next.Cursor.Add(-s.options.Lag)) {
+			delete(next.Seen, key)
+		}
+	}
+
+	if handler != nil && len(newResults) > 0 {
+		if err := handler(newResults); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.options.Store.Save(ctx, s.options.Key, next); err != nil {
+		return nil, fmt.Errorf("failed to save checkpoint: %s", err)
+	}
+	s.checkpoint = next
+	return newResults, nil
+}
+
+// search Runs the query from earliest up to latest of the cursor field and returns the results oldest first
+func (s *IncrementalSearch) search(ctx context.Context, earliest, latest time.Time) ([]SearchResult, error) {
+	params := map[string]string{}
+	for key, value := range s.options.Params {
+		params[key] = value
+	}
+	query := s.query
+	if s.options.CursorField == CursorIndexTime {
+		params["index_earliest"] = formatEpoch(earliest)
+		params["index_latest"] = formatEpoch(latest)
+		query += " | eval " + incrementalIndexTimeField + "=_indextime"
+	} else {
+		params["earliest_time"] = formatEpoch(earliest)
+		params["latest_time"] = formatEpoch(latest)
+	}
+
+	search, err := s.client.CreateSearchJob(ctx, query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create incremental search job: %s", err)
+	}
+	defer search.Delete(context.Background())
+	if err := search.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("failed waiting on incremental search: %s", err)
+	}
+	resultsChan, err := search.GetResults(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results := []SearchResult{}
+	for result := range resultsChan {
+		results = append(results, result)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	sortResultsByTime(results)
+	return results, nil
+}
+
+// resultCursor Returns the cursor time of a result and removes the copied index time field
+func (s *IncrementalSearch) resultCursor(result SearchResult) (time.Time, error) {
+	if s.options.CursorField == CursorIndexTime {
+		value := result[incrementalIndexTimeField]
+		delete(result, incrementalIndexTimeField)
+		return ParseAnyTime(value)
+	}
+	return ParseAnyTime(result[CursorEventTime])
+}
+
+// defaultResultKey Identifies an event by its bucket and offset, or by a hash of all its fields
+func defaultResultKey(result SearchResult) string {
+	cd, hasCD := result["_cd"]
+	bkt, hasBkt := result["_bkt"]
+	if hasCD && hasBkt {
+		return fmt.Sprintf("%v|%v", bkt, cd)
+	}
+	copied := make(map[string]interface{}, len(result))
+	for key, value := range result {
+		if key != incrementalIndexTimeField {
+			copied[key] = value
+		}
+	}
+	// Map keys are sorted when marshaled, so the hash is stable
+	data, _ := json.Marshal(copied)
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// formatEpoch Formats the time as epoch seconds with milliseconds
+func formatEpoch(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano()/int64(time.Millisecond))/1000, 'f', 3, 64)
+}
@@ -0,0 +1,185 @@
+package splunk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// incrementalTestServer is a fake splunk that returns the events indexed within each job's index time range
+type incrementalTestServer struct {
+	*httptest.Server
+	lock sync.Mutex
+	// Events by index time in epoch seconds
+	events map[float64]SearchResult
+	jobs   map[string]url.Values
+}
+
+func newIncrementalTestServer() *incrementalTestServer {
+	s := &incrementalTestServer{events: map[float64]SearchResult{}, jobs: map[string]url.Values{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		path := strings.TrimPrefix(req.URL.Path, "/services/search/jobs")
+		switch {
+		case req.Method == http.MethodPost && path == "":
+			b, _ := ioutil.ReadAll(req.Body)
+			values, _ := url.ParseQuery(string(b))
+			sid := fmt.Sprintf("job_%d", len(s.jobs))
+			s.jobs[sid] = values
+			rw.WriteHeader(http.StatusCreated)
+			rw.Write([]byte(`{"sid":"` + sid + `"}`))
+		case req.Method == http.MethodDelete:
+		case strings.HasSuffix(path, "/results_preview"):
+			job := s.jobs[strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/results_preview")]
+			results := []SearchResult{}
+			if req.URL.Query().Get("offset") == "0" {
+				earliest, _ := strconv.ParseFloat(job.Get("index_earliest"), 64)
+				latest, _ := strconv.ParseFloat(job.Get("index_latest"), 64)
+				for indexTime, event := range s.events {
+					if indexTime >= earliest && indexTime < latest {
+						result := SearchResult{}
+						for key, value := range event {
+							result[key] = value
+						}
+						result[incrementalIndexTimeField] = strconv.FormatFloat(indexTime, 'f', -1, 64)
+						results = append(results, result)
+					}
+				}
+			}
+			json.NewEncoder(rw).Encode(SearchResults{Results: results})
+		default:
+			rw.Write([]byte(`{"entry":[{"content":{"dispatchState":"DONE"}}]}`))
+		}
+	}))
+	return s
+}
+
+func (s *incrementalTestServer) index(indexTime time.Time, raw string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.events[float64(indexTime.Unix())] = SearchResult{"_time": FormatTime(indexTime), "_raw": raw}
+}
+
+func TestIncrementalSearch(t *testing.T) {
+	server := newIncrementalTestServer()
+	defer server.Close()
+	client := &Client{
+		config: &Config{
+			BaseURL:    server.URL,
+			HTTPClient: http.DefaultClient,
+		},
+	}
+
+	start := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	store := NewMemoryCheckpointStore()
+	newSearch := func() *IncrementalSearch {
+		search, err := client.NewIncrementalSearch("index=main", &IncrementalSearchOptions{
+			Key:   "main",
+			Store: store,
+			Lag:   time.Minute,
+		})
+		require.NoError(t, err)
+		search.now = func() time.Time { return now }
+		return search
+	}
+	raws := func(results []SearchResult) []string {
+		values := []string{}
+		for _, result := range results {
+			values = append(values, result["_raw"].(string))
+			require.NotContains(t, result, incrementalIndexTimeField)
+		}
+		return values
+	}
+
+	search := newSearch()
+	server.index(start.Add(-time.Second*10), "before")
+	results, err := search.Poll(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"before"}, raws(results))
+
+	// Events in the lag are found again but not emitted twice
+	server.index(start.Add(time.Second*30), "first")
+	server.index(start.Add(time.Second*50), "second")
+	now = start.Add(time.Minute)
+	results, err = search.Poll(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second"}, raws(results))
+	require.Equal(t, now, search.Checkpoint().Cursor)
+
+	// A late event indexed within the lag is still found
+	server.index(start.Add(time.Second*55), "late")
+	now = start.Add(time.Minute * 2)
+	results, err = search.Poll(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"late"}, raws(results))
+	for _, cursor := range search.Checkpoint().Seen {
+		require.False(t, cursor.Before(now.Add(-time.Minute)))
+	}
+
+	// A new search resumes from the saved checkpoint
+	server.index(start.Add(time.Second*90), "third")
+	search = newSearch()
+	results, err = search.Poll(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"third"}, raws(results))
+
+	// A failed handler does not move the checkpoint, so the results are handled again
+	server.index(start.Add(time.Second*130), "fourth")
+	now = start.Add(time.Minute * 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	require.Error(t, search.Run(ctx, 0, func(results []SearchResult) error { return nil }))
+	err = search.Run(ctx, time.Millisecond, func(results []SearchResult) error {
+		return fmt.Errorf("failed")
+	})
+	require.EqualError(t, err, "failed")
+	handled := []string{}
+	err = search.Run(ctx, time.Millisecond, func(results []SearchResult) error {
+		handled = append(handled, raws(results)...)
+		cancel()
+		return nil
+	})
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, []string{"fourth"}, handled)
+
+	require.Equal(t, "search index=main | eval "+incrementalIndexTimeField+"=_indextime", server.jobs["job_0"].Get("search"))
+	require.Equal(t, "1598918340.000", server.jobs["job_0"].Get("index_earliest"))
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoints")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := &FileCheckpointStore{Dir: dir}
+	checkpoint, err := store.Load(context.Background(), "a/b")
+	require.NoError(t, err)
+	require.Nil(t, checkpoint)
+
+	cursor := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, store.Save(context.Background(), "a/b", &Checkpoint{Cursor: cursor, Seen: map[string]time.Time{"key": cursor}}))
+	checkpoint, err = store.Load(context.Background(), "a/b")
+	require.NoError(t, err)
+	require.True(t, cursor.Equal(checkpoint.Cursor))
+	require.Len(t, checkpoint.Seen, 1)
+}
+
+func TestDefaultResultKey(t *testing.T) {
+	require.Equal(t, "1|2", defaultResultKey(SearchResult{"_bkt": "1", "_cd": "2", "_raw": "a"}))
+	require.Equal(t, defaultResultKey(SearchResult{"_raw": "a", "host": "b"}), defaultResultKey(SearchResult{"host": "b", "_raw": "a"}))
+	require.NotEqual(t, defaultResultKey(SearchResult{"_raw": "a"}), defaultResultKey(SearchResult{"_raw": "b"}))
+}
@@ -0,0 +1,248 @@
+package splunk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	kvStoreConfigSuffix = "/servicesNS/%s/%s/storage/collections/config"
+
+	// Owner of collections shared with every user of the app
+	defaultKVStoreOwner = "nobody"
+)
+
+// KVStoreCollection is the configuration of a KV Store collection
+//
+// Settings left unset (nil, empty or 0) are not sent, so splunk's defaults apply on create and an update keeps their current values.
+// Any other parameter from [the documentation](https://docs.splunk.com/Documentation/Splunk/8.0.5/RESTREF/RESTkvstore#storage.2Fcollections.2Fconfig)
+// can be set with Params.
+type KVStoreCollection struct {
+	Name string
+	// Namespace of the collection.  Owner defaults to "nobody", which shares the collection with every user of the app
+	Owner string
+	App   string
+
+	// Types of the fields by name: "number", "bool", "string", "time", "array" or "cidr"
+	Fields map[string]string
+	// Reject records with fields that do not match their type in Fields
+	EnforceTypes *bool
+
+	// Accelerated fields (indexes) by name.  Each is a JSON object of the fields it covers, 1 for ascending or -1 for descending,
+	// ex: {"host": 1, "time": -1}
+	AcceleratedFields map[string]string
+
+	// Replicate the collection to the indexers so lookups on it can run there
+	Replicate *bool
+	// How the collection is replicated, "one_file" or "auto"
+	ReplicationDumpStrategy string
+	// Size in KB of each replication file when ReplicationDumpStrategy is "auto"
+	ReplicationDumpMaximumFileSize int
+
+	// ACL is filled in when fetching, it is not sent when creating or updating
+	ACL ACL
+
+	// Params are any other parameters to send when creating or updating
+	Params map[string]string
+}
+
+// kvStoreCollectionsResponse is what splunk returns when fetching collections
+type kvStoreCollectionsResponse struct {
+	Entry []struct {
+		Name    string                 `json:"name"`
+		ACL     ACL                    `json:"acl"`
+		Content map[string]interface{} `json:"content"`
+	} `json:"entry"`
+}
+
+// kvStoreConfigPath Returns the suffix of the collections config in the namespace, and of the named collection if name is set
+func kvStoreConfigPath(owner, app, name string) (string, error) {
+	if app == "" {
+		return "", fmt.Errorf("the app of the collection is required")
+	}
+	if owner == "" {
+		owner = defaultKVStoreOwner
+	}
+	suffix := fmt.Sprintf(kvStoreConfigSuffix, url.PathEscape(owner), url.PathEscape(app))
+	if name != "" {
+		suffix += "/" + url.PathEscape(name)
+	}
+	return suffix, nil
+}
+
+// params Converts the collection to the parameters to send to splunk
+func (k *KVStoreCollection) params() map[string]string {
+	params := map[string]string{}
+	if k.EnforceTypes != nil {
+		params["enforceTypes"] = strconv.FormatBool(*k.EnforceTypes)
+	}
+	if k.Replicate != nil {
+		params["replicate"] = strconv.FormatBool(*k.Replicate)
+	}
+	if k.ReplicationDumpStrategy != "" {
+		params["replication_dump_strategy"] = k.ReplicationDumpStrategy
+	}
+	if k.ReplicationDumpMaximumFileSize != 0 {
+		params["replication_dump_maximum_file_size"] = strconv.Itoa(k.ReplicationDumpMaximumFileSize)
+	}
+	for field, fieldType := range k.Fields {
+		params["field."+field] = fieldType
+	}
+	for name, fields := range k.AcceleratedFields {
+		params["accelerated_fields."+name] = fields
+	}
+	for key, value := range k.Params {
+		params[key] = value
+	}
+	return params
+}
+
+// CreateKVStoreCollection Creates a new collection in the collection's Owner and App
+func (c *Client) CreateKVStoreCollection(ctx context.Context, collection *KVStoreCollection) error {
+	suffix, err := kvStoreConfigPath(collection.Owner, collection.App, "")
+	if err != nil {
+		return err
+	}
+	params := collection.params()
+	params["name"] = collection.Name
+
+	resp, err := c.BuildResponse(ctx, http.MethodPost, suffix, params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 201 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("bad status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// GetKVStoreCollection Gets a single collection by name.  An empty owner is "nobody"
+func (c *Client) GetKVStoreCollection(ctx context.Context, owner, app, name string) (*KVStoreCollection, error) {
+	suffix, err := kvStoreConfigPath(owner, app, name)
+	if err != nil {
+		return nil, err
+	}
+	collections, err := c.getKVStoreCollections(ctx, suffix, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(collections) == 0 {
+		return nil, fmt.Errorf("no collection found")
+	}
+	return collections[0], nil
+}
+
+// ListKVStoreCollections Lists the collections visible in the namespace.  An empty owner is "nobody", use "-" for any owner or app
+//
+// Params are any other parameters you want to specific, such as "search" to filter the results
+func (c *Client) ListKVStoreCollections(ctx context.Context, owner, app string, params map[string]string) ([]*KVStoreCollection, error) {
+	suffix, err := kvStoreConfigPath(owner, app, "")
+	if err != nil {
+		return nil, err
+	}
+	paramsToSend := map[string]string{"count": "0"}
+	for key, value := range params {
+		paramsToSend[key] = value
+	}
+	return c.getKVStoreCollections(ctx, suffix, paramsToSend)
+}
+
+// getKVStoreCollections fetches and parses the collections at the suffix
+func (c *Client) getKVStoreCollections(ctx context.Context, suffix string, params map[string]string) ([]*KVStoreCollection, error) {
+	resp, err := c.BuildResponse(ctx, http.MethodGet, suffix, params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("bad status code: %d", resp.StatusCode)
+	}
+
+	result := kvStoreCollectionsResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %s", err)
+	}
+
+	collections := make([]*KVStoreCollection, 0, len(result.Entry))
+	for _, entry := range result.Entry {
+		collection := &KVStoreCollection{
+			Name:              entry.Name,
+			Owner:             entry.ACL.Owner,
+			App:               entry.ACL.App,
+			ACL:               entry.ACL,
+			Fields:            map[string]string{},
+			AcceleratedFields: map[string]string{},
+		}
+		for key, value := range entry.Content {
+			if value == nil {
+				continue
+			}
+			switch {
+			case strings.HasPrefix(key, "field."):
+				collection.Fields[strings.TrimPrefix(key, "field.")] = fmt.Sprintf("%v", value)
+			case strings.HasPrefix(key, "accelerated_fields."):
+				collection.AcceleratedFields[strings.TrimPrefix(key, "accelerated_fields.")] = fmt.Sprintf("%v", value)
+			case key == "enforceTypes":
+				if enforceTypes, err := getBoolValue(value); err == nil {
+					collection.EnforceTypes = &enforceTypes
+				}
+			case key == "replicate":
+				if replicate, err := getBoolValue(value); err == nil {
+					collection.Replicate = &replicate
+				}
+			case key == "replication_dump_strategy":
+				collection.ReplicationDumpStrategy = fmt.Sprintf("%v", value)
+			case key == "replication_dump_maximum_file_size":
+				size, _ := getFloatValue(value)
+				collection.ReplicationDumpMaximumFileSize = int(size)
+			}
+		}
+		collections = append(collections, collection)
+	}
+
+	return collections, nil
+}
+
+// UpdateKVStoreCollection Updates an existing collection with the settings set in collection.
+// Settings, fields and accelerated fields that are not set are left as they are.
+func (c *Client) UpdateKVStoreCollection(ctx context.Context, collection *KVStoreCollection) error {
+	suffix, err := kvStoreConfigPath(collection.Owner, collection.App, collection.Name)
+	if err != nil {
+		return err
+	}
+	resp, err := c.BuildResponse(ctx, http.MethodPost, suffix, collection.params())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("bad status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// DeleteKVStoreCollection Deletes a collection and all of its data.  An empty owner is "nobody"
+func (c *Client) DeleteKVStoreCollection(ctx context.Context, owner, app, name string) error {
+	suffix, err := kvStoreConfigPath(owner, app, name)
+	if err != nil {
+		return err
+	}
+	resp, err := c.BuildResponse(ctx, http.MethodDelete, suffix, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("bad status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package splunk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	kvStoreDataSuffix = "/servicesNS/%s/%s/storage/collections/data/%s"
+
+	// Splunk's default limit of records per batch_save, max_documents_per_batch_save in limits.conf
+	defaultKVStoreBatchSize = 1000
+)
+
+// ErrKVStoreRecordNotFound is returned when a record with the key does not exist
+var ErrKVStoreRecordNotFound = errors.New("record not found")
+
+// KVStoreData reads and writes the records of a KV Store collection.
+//
+// Records are sent and decoded as JSON, so use json tags on record structs.  The key of a record is its "_key" field.
+type KVStoreData struct {
+	// Number of records sent in each batch_save request by BatchSave.  Default: 1000
+	BatchSize int
+
+	client *Client
+	suffix string
+}
+
+// KVStoreQuery selects the records of a KV Store query
+type KVStoreQuery struct {
+	// The JSON query, such as map[string]interface{}{"age": map[string]interface{}{"$gt": 30}}.  Nil matches every record
+	Query interface{}
+	// Fields to sort by, each optionally followed by ":1" for ascending or ":-1" for descending, ex: []string{"name", "age:-1"}
+	Sort []string
+	// Number of records to skip
+	Skip int
+	// Maximum number of records to return, 0 for no limit
+	Limit int
+	// Fields to return, or to leave out with ":0", ex: []string{"name", "age"}.  Empty returns every field
+	Fields []string
+}
+
+// KVStoreData Returns the data of the collection in the namespace.  An empty owner is "nobody"
+func (c *Client) KVStoreData(owner, app, collection string) (*KVStoreData, error) {
+	if app == "" {
+		return nil, fmt.Errorf("the app of the collection is required")
+	}
+	if collection == "" {
+		return nil, fmt.Errorf("the collection is required")
+	}
+	if owner == "" {
+		owner = defaultKVStoreOwner
+	}
+	return &KVStoreData{
+		client: c,
+		suffix: fmt.Sprintf(kvStoreDataSuffix, url.PathEscape(owner), url.PathEscape(app), url.PathEscape(collection)),
+	}, nil
+}
+
+// kvStoreStatusError is an unexpected status code from the KV Store
+type kvStoreStatusError struct {
+	statusCode int
+	body       string
+}
+
+func (e *kvStoreStatusError) Error() string {
+	return fmt.Sprintf("bad status code: %d, body: %s", e.statusCode, e.body)
+}
+
+// recordError Returns ErrKVStoreRecordNotFound if the error is a 404 for a single record
+func recordError(err error) error {
+	if statusErr, ok := err.(*kvStoreStatusError); ok && statusErr.statusCode == http.StatusNotFound {
+		return ErrKVStoreRecordNotFound
+	}
+	return err
+}
+
+// recordSuffix Returns the suffix of the record with the key.  An empty key is an error, it would address the whole collection
+func (k *KVStoreData) recordSuffix(key string) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("the key of the record is required")
+	}
+	return k.suffix + "/" + url.PathEscape(key), nil
+}
+
+// do Makes a JSON request and decodes the response into result if it is not nil
+func (k *KVStoreData) do(ctx context.Context, method, suffix string, params map[string]string, body interface{}, result interface{}, expectedStatus ...int) error {
+	resp, err := k.client.BuildJSONResponse(ctx, method, suffix, params, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	ok := false
+	for _, status := range expectedStatus {
+		ok = ok || resp.StatusCode == status
+	}
+	if !ok {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return &kvStoreStatusError{statusCode: resp.StatusCode, body: string(respBody)}
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to unmarshal: %s", err)
+	}
+	return nil
+}
+
+// Insert Adds the record and returns its key.  A key is generated unless the record has a _key
+func (k *KVStoreData) Insert(ctx context.Context, record interface{}) (string, error) {
+	result := struct {
+		Key string `json:"_key"`
+	}{}
+	if err := k.do(ctx, http.MethodPost, k.suffix, nil, record, &result, http.StatusOK, http.StatusCreated); err != nil {
+		return "", err
+	}
+	return result.Key, nil
+}
+
+// Get Decodes the record with the key into v.  It returns ErrKVStoreRecordNotFound if there is no such record
+func (k *KVStoreData) Get(ctx context.Context, key string, v interface{}) error {
+	suffix, err := k.recordSuffix(key)
+	if err != nil {
+		return err
+	}
+	return recordError(k.do(ctx, http.MethodGet, suffix, nil, nil, v, http.StatusOK))
+}
+
+// Update Replaces the record with the key.  It returns ErrKVStoreRecordNotFound if there is no such record
+func (k *KVStoreData) Update(ctx context.Context, key string, record interface{}) error {
+	suffix, err := k.recordSuffix(key)
+	if err != nil {
+		return err
+	}
+	return recordError(k.do(ctx, http.MethodPost, suffix, nil, record, nil, http.StatusOK, http.StatusCreated))
+}
+
+// Delete Deletes the record with the key.  It returns ErrKVStoreRecordNotFound if there is no such record
+func (k *KVStoreData) Delete(ctx context.Context, key string) error {
+	suffix, err := k.recordSuffix(key)
+	if err != nil {
+		return err
+	}
+	return recordError(k.do(ctx, http.MethodDelete, suffix, nil, nil, nil, http.StatusOK))
+}
+
+// DeleteWhere Deletes every record matching the JSON query.  A nil query deletes every record of the collection
+func (k *KVStoreData) DeleteWhere(ctx context.Context, query interface{}) error {
+	params := map[string]string{}
+	if query != nil {
+		queryJSON, err := json.Marshal(query)
+		if err != nil {
+			return err
+		}
+		params["query"] = string(queryJSON)
+	}
+	return k.do(ctx, http.MethodDelete, k.suffix, params, nil, nil, http.StatusOK)
+}
+
+// Query Decodes the records selected by the query into the slice v points to, such as *[]MyRecord.  A nil query returns every record
+func (k *KVStoreData) Query(ctx context.Context, query *KVStoreQuery, v interface{}) error {
+	if query == nil {
+		query = &KVStoreQuery{}
+	}
+	params := map[string]string{}
+	if query.Query != nil {
+		queryJSON, err := json.Marshal(query.Query)
+		if err != nil {
+			return err
+		}
+		params["query"] = string(queryJSON)
+	}
+	if len(query.Sort) > 0 {
+		params["sort"] = strings.Join(query.Sort, ",")
+	}
+	if query.Skip > 0 {
+		params["skip"] = strconv.Itoa(query.Skip)
+	}
+	if query.Limit > 0 {
+		params["limit"] = strconv.Itoa(query.Limit)
+	}
+	if len(query.Fields) > 0 {
+		params["fields"] = strings.Join(query.Fields, ",")
+	}
+	return k.do(ctx, http.MethodGet, k.suffix, params, nil, v, http.StatusOK)
+}
+
+// BatchSave Inserts or replaces the records of a slice, such as []MyRecord, and returns their keys in order.
+//
+// Records with a _key replace the record with that key.  The records are sent BatchSize at a time,
+// so if a batch fails the batches before it are saved.  The keys of the saved records are returned with the error.
+func (k *KVStoreData) BatchSave(ctx context.Context, records interface{}) ([]string, error) {
+	sliceValue := reflect.ValueOf(records)
+	if sliceValue.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("records must be a slice, got %T", records)
+	}
+	batchSize := k.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultKVStoreBatchSize
+	}
+
+	keys := []string{}
+	for start := 0; start < sliceValue.Len(); start += batchSize {
+		end := start + batchSize
+		if end > sliceValue.Len() {
+			end = sliceValue.Len()
+		}
+		batchKeys := []string{}
+		err := k.do(ctx, http.MethodPost, k.suffix+"/batch_save", nil, sliceValue.Slice(start, end).Interface(), &batchKeys, http.StatusOK, http.StatusCreated)
+		if err != nil {
+			return keys, fmt.Errorf("failed to save records %d to %d: %s", start, end, err)
+		}
+		keys = append(keys, batchKeys...)
+	}
+	return keys, nil
+}
@@ -0,0 +1,194 @@
+package splunk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type kvStoreTestRecord struct {
+	Key  string `json:"_key,omitempty"`
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+// newKVStoreTestServer is a fake KV Store collection that records the requests it gets
+func newKVStoreTestServer() (*httptest.Server, *[]*http.Request) {
+	lock := sync.Mutex{}
+	records := map[string]json.RawMessage{}
+	requests := []*http.Request{}
+	prefix := "/servicesNS/nobody/search/storage/collections/data/users"
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		requests = append(requests, req)
+		if req.URL.Query().Get("output_mode") != "json" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		path := strings.TrimPrefix(req.URL.Path, prefix)
+		switch {
+		case req.Method == http.MethodPost && path == "/batch_save":
+			batch := []kvStoreTestRecord{}
+			if req.Header.Get("Content-Type") != "application/json" || json.NewDecoder(req.Body).Decode(&batch) != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			keys := []string{}
+			for _, record := range batch {
+				if record.Key == "" {
+					record.Key = fmt.Sprintf("key%d", len(records))
+				}
+				records[record.Key], _ = json.Marshal(record)
+				keys = append(keys, record.Key)
+			}
+			json.NewEncoder(rw).Encode(keys)
+		case req.Method == http.MethodPost && path == "":
+			record := kvStoreTestRecord{}
+			if json.NewDecoder(req.Body).Decode(&record) != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if record.Key == "" {
+				record.Key = fmt.Sprintf("key%d", len(records))
+			}
+			records[record.Key], _ = json.Marshal(record)
+			rw.WriteHeader(http.StatusCreated)
+			rw.Write([]byte(`{"_key":"` + record.Key + `"}`))
+		case path == "":
+			// Queries only support an exact name match in this fake
+			query := map[string]string{}
+			if req.URL.Query().Get("query") != "" && json.Unmarshal([]byte(req.URL.Query().Get("query")), &query) != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			matches := []json.RawMessage{}
+			for key, record := range records {
+				decoded := kvStoreTestRecord{}
+				json.Unmarshal(record, &decoded)
+				if name, ok := query["name"]; ok && decoded.Name != name {
+					continue
+				}
+				if req.Method == http.MethodDelete {
+					delete(records, key)
+					continue
+				}
+				matches = append(matches, record)
+			}
+			if req.Method == http.MethodGet {
+				json.NewEncoder(rw).Encode(matches)
+			}
+		default:
+			key, _ := url.PathUnescape(strings.TrimPrefix(path, "/"))
+			if _, ok := records[key]; !ok {
+				rw.WriteHeader(http.StatusNotFound)
+				rw.Write([]byte(`{"messages":[{"type":"ERROR","text":"Could not find object."}]}`))
+				return
+			}
+			switch req.Method {
+			case http.MethodGet:
+				rw.Write(records[key])
+			case http.MethodPost:
+				record := kvStoreTestRecord{}
+				if json.NewDecoder(req.Body).Decode(&record) != nil {
+					rw.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				record.Key = key
+				records[key], _ = json.Marshal(record)
+			case http.MethodDelete:
+				delete(records, key)
+			}
+		}
+	}))
+	return server, &requests
+}
+
+func TestKVStoreData(t *testing.T) {
+	server, requests := newKVStoreTestServer()
+	defer server.Close()
+	client := &Client{
+		config: &Config{
+			BaseURL:    server.URL,
+			HTTPClient: http.DefaultClient,
+		},
+	}
+	data, err := client.KVStoreData("", "search", "users")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	key, err := data.Insert(ctx, &kvStoreTestRecord{Name: "alice", Age: 30})
+	require.NoError(t, err)
+	require.Equal(t, "key0", key)
+
+	record := kvStoreTestRecord{}
+	require.NoError(t, data.Get(ctx, key, &record))
+	require.Equal(t, kvStoreTestRecord{Key: key, Name: "alice", Age: 30}, record)
+
+	require.NoError(t, data.Update(ctx, key, &kvStoreTestRecord{Name: "alice", Age: 31}))
+	require.NoError(t, data.Get(ctx, key, &record))
+	require.Equal(t, 31, record.Age)
+
+	require.Equal(t, ErrKVStoreRecordNotFound, data.Get(ctx, "missing/key", &record))
+	require.Equal(t, ErrKVStoreRecordNotFound, data.Update(ctx, "missing", &record))
+
+	// An empty key is never sent, it would address the whole collection
+	sent := len(*requests)
+	require.Error(t, data.Get(ctx, "", &record))
+	require.Error(t, data.Update(ctx, "", &record))
+	require.Error(t, data.Delete(ctx, ""))
+	require.Len(t, *requests, sent)
+	require.NoError(t, data.Delete(ctx, key))
+	require.Equal(t, ErrKVStoreRecordNotFound, data.Delete(ctx, key))
+
+	t.Run("batch save", func(t *testing.T) {
+		data.BatchSize = 2
+		before := len(*requests)
+		keys, err := data.BatchSave(ctx, []kvStoreTestRecord{{Key: "b", Name: "bob"}, {Key: "c", Name: "carol"}, {Key: "d", Name: "bob"}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"b", "c", "d"}, keys)
+		require.Len(t, *requests, before+2)
+
+		_, err = data.BatchSave(ctx, kvStoreTestRecord{})
+		require.Error(t, err)
+	})
+
+	t.Run("query", func(t *testing.T) {
+		records := []kvStoreTestRecord{}
+		err := data.Query(ctx, &KVStoreQuery{
+			Query:  map[string]interface{}{"name": "bob"},
+			Sort:   []string{"name", "age:-1"},
+			Skip:   1,
+			Limit:  10,
+			Fields: []string{"name", "_key"},
+		}, &records)
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+
+		query := (*requests)[len(*requests)-1].URL.Query()
+		require.Equal(t, `{"name":"bob"}`, query.Get("query"))
+		require.Equal(t, "name,age:-1", query.Get("sort"))
+		require.Equal(t, "1", query.Get("skip"))
+		require.Equal(t, "10", query.Get("limit"))
+		require.Equal(t, "name,_key", query.Get("fields"))
+	})
+
+	t.Run("delete where", func(t *testing.T) {
+		require.NoError(t, data.DeleteWhere(ctx, map[string]interface{}{"name": "bob"}))
+		records := []kvStoreTestRecord{}
+		require.NoError(t, data.Query(ctx, nil, &records))
+		require.Equal(t, []kvStoreTestRecord{{Key: "c", Name: "carol"}}, records)
+	})
+
+	_, err = client.KVStoreData("", "", "users")
+	require.Error(t, err)
+}
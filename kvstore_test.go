@@ -0,0 +1,136 @@
+package splunk
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_CreateKVStoreCollection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method != http.MethodPost:
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		case req.URL.Path != "/servicesNS/nobody/my app/storage/collections/config":
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		b, _ := ioutil.ReadAll(req.Body)
+		values, _ := url.ParseQuery(string(b))
+		if values.Get("name") != "users" ||
+			values.Get("field.name") != "string" ||
+			values.Get("field.age") != "number" ||
+			values.Get("accelerated_fields.by_name") != `{"name": 1}` ||
+			values.Get("replicate") != "true" ||
+			values["enforceTypes"] != nil ||
+			values.Get("replication_dump_strategy") != "auto" ||
+			values.Get("replication_dump_maximum_file_size") != "" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	client := &Client{
+		config: &Config{
+			BaseURL:    server.URL,
+			HTTPClient: http.DefaultClient,
+		},
+	}
+
+	err := client.CreateKVStoreCollection(context.Background(), &KVStoreCollection{
+		Name:                    "users",
+		App:                     "my app",
+		Fields:                  map[string]string{"name": "string", "age": "number"},
+		AcceleratedFields:       map[string]string{"by_name": `{"name": 1}`},
+		Replicate:               Bool(true),
+		ReplicationDumpStrategy: "auto",
+	})
+	require.NoError(t, err)
+
+	err = client.CreateKVStoreCollection(context.Background(), &KVStoreCollection{Name: "users"})
+	require.Error(t, err)
+}
+
+func TestClient_GetKVStoreCollection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet || req.URL.Path != "/servicesNS/admin/search/storage/collections/config/users" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw.Write([]byte(`{"entry":[{"name":"users","acl":{"app":"search","owner":"admin","sharing":"app"},"content":{
+			"field.name":"string",
+			"field.age":"number",
+			"accelerated_fields.by_name":"{\"name\": 1}",
+			"enforceTypes":"1",
+			"replicate":false,
+			"replication_dump_strategy":"one_file",
+			"replication_dump_maximum_file_size":"10240",
+			"disabled":false
+		}}]}`))
+	}))
+	defer server.Close()
+	client := &Client{
+		config: &Config{
+			BaseURL:    server.URL,
+			HTTPClient: http.DefaultClient,
+		},
+	}
+
+	collection, err := client.GetKVStoreCollection(context.Background(), "admin", "search", "users")
+	require.NoError(t, err)
+	require.Equal(t, &KVStoreCollection{
+		Name:                           "users",
+		Owner:                          "admin",
+		App:                            "search",
+		Fields:                         map[string]string{"name": "string", "age": "number"},
+		EnforceTypes:                   Bool(true),
+		Replicate:                      Bool(false),
+		AcceleratedFields:              map[string]string{"by_name": `{"name": 1}`},
+		ReplicationDumpStrategy:        "one_file",
+		ReplicationDumpMaximumFileSize: 10240,
+		ACL:                            ACL{App: "search", Owner: "admin", Sharing: "app"},
+	}, collection)
+}
+
+func TestClient_UpdateAndDeleteKVStoreCollection(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		if req.Method == http.MethodPost {
+			b, _ := ioutil.ReadAll(req.Body)
+			values, _ := url.ParseQuery(string(b))
+			if len(values) != 3 || values.Get("output_mode") != "json" || values.Get("accelerated_fields.by_email") != `{"email": 1}` || values.Get("field.email") != "string" {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := &Client{
+		config: &Config{
+			BaseURL:    server.URL,
+			HTTPClient: http.DefaultClient,
+		},
+	}
+
+	require.NoError(t, client.UpdateKVStoreCollection(context.Background(), &KVStoreCollection{
+		Name:              "users",
+		App:               "search",
+		Fields:            map[string]string{"email": "string"},
+		AcceleratedFields: map[string]string{"by_email": `{"email": 1}`},
+	}))
+	require.NoError(t, client.DeleteKVStoreCollection(context.Background(), "", "search", "users"))
+	require.Equal(t, []string{
+		"POST /servicesNS/nobody/search/storage/collections/config/users",
+		"DELETE /servicesNS/nobody/search/storage/collections/config/users",
+	}, requests)
+}
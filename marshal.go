@@ -1,68 +1,453 @@
 package splunk
 
 import (
+	"encoding"
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	unmarshalerType     = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Unmarshaler is implemented by types that can decode themselves from a splunk field value in UnMarshal.
+//
+// The value is what splunk returned for the field: a string, float64, bool, nil, or []interface{} for multi-value fields
+type Unmarshaler interface {
+	UnmarshalSplunk(value interface{}) error
+}
+
+const (
+	// eventTimeLabel is the label of the time.Time field used as the event time when marshaling
+	eventTimeLabel = "_time"
+)
+
+// fieldTag is a parsed `splunk:"name,option,..."` struct tag
+type fieldTag struct {
+	// The splunk field name, the struct field name if it is not set
+	name string
+	// Skip the field, from a tag of "-"
+	skip bool
+	// Do not marshal the field if it is the zero value
+	omitEmpty bool
+	// Marshal the field as an indexed field instead of part of the event
+	indexed bool
+	// Separator to join a multi-value field into a string field
+	join string
+	// Separator to split a string into a slice field
+	split string
+	// Return an error when decoding if the field is missing
+	required bool
+	// Layout of time fields, or EpochTimeFormat.  If empty, times are parsed with ParseAnyTime
+	timeFormat string
+}
+
+// tagSeparators are names for separators that can not be written in a tag
+var tagSeparators = map[string]string{
+	"comma":   ",",
+	"space":   " ",
+	"newline": "\n",
+	"tab":     "\t",
+}
+
+// parseTagSeparator Gets the separator from a join or split option
+func parseTagSeparator(value string) string {
+	if separator, ok := tagSeparators[value]; ok {
+		return separator
+	}
+	return value
+}
+
+// parseFieldTag Parses the `splunk` tag of a struct field
+func parseFieldTag(field reflect.StructField) fieldTag {
+	tag := fieldTag{name: field.Name, join: "\n"}
+	splunkTag := field.Tag.Get("splunk")
+	if splunkTag == "-" {
+		tag.skip = true
+		return tag
+	}
+
+	parts := strings.Split(splunkTag, ",")
+	if parts[0] != "" {
+		tag.name = parts[0]
+	}
+	for i, option := range parts[1:] {
+		if strings.HasPrefix(option, "timeformat=") {
+			// Layouts can have commas, so the layout is the rest of the tag
+			tag.timeFormat = strings.TrimPrefix(strings.Join(parts[i+1:], ","), "timeformat=")
+			break
+		}
+		switch option {
+		case "omitempty":
+			tag.omitEmpty = true
+		case "indexed":
+			tag.indexed = true
+		case "required":
+			tag.required = true
+		default:
+			if strings.HasPrefix(option, "join=") {
+				tag.join = parseTagSeparator(strings.TrimPrefix(option, "join="))
+			} else if strings.HasPrefix(option, "split=") {
+				tag.split = parseTagSeparator(strings.TrimPrefix(option, "split="))
+			}
+		}
+	}
+	return tag
+}
+
 // UnMarshal Fills in a struct with the splunk result.  The passed in interface must be a pointer to a struct
 //
-// It will fill each field in the struct looking for the "splunk" tag on the field based on the splunk field
+// It will fill each field in the struct looking for the "splunk" tag on the field based on the splunk field.
+// Fields tagged with "-" are skipped.
+//
+// Multi-value fields fill slice fields, such as []string or []int, converting each value.  They are joined with
+// newlines into string fields, or the separator from a `join=` tag option.  A `split=` tag option splits a string
+// into a slice field.  The separators "comma", "space", "newline" and "tab" can be used by name, ex: `splunk:"hosts,split=comma"`
+//
+// Fields whose type implements Unmarshaler or encoding.TextUnmarshaler decode themselves.
+// Pointer fields are left nil if the field is not in the result.  time.Duration fields are parsed from seconds.
+// Nested struct fields are filled from the splunk fields starting with "label.", such as "src.ip" for a struct
+// field tagged "src".  The fields of embedded structs are filled as if they were in the outer struct.
+//
+// A `required` tag option returns an error if the field is missing from the result.
+//
+// time.Time fields are parsed with ParseAnyTime, or with the Go layout from a `timeformat=` tag option, which must
+// be the last option, ex: `splunk:"created,timeformat=Jan 2, 2006"`.  Use `timeformat=epoch` for epoch seconds.
+//
+// All field errors are returned together as a *DecodeError.  Use a Decoder for strict decoding.
 func (e SearchResult) UnMarshal(v interface{}) error {
-	typeOf := reflect.TypeOf(v).Elem()
-	valueOf := reflect.ValueOf(v).Elem()
+	return (&Decoder{}).Decode(e, v)
+}
+
+// structPlan is how to fill a struct type, computed once per type and cached
+type structPlan struct {
+	fields []*fieldPlan
+}
+
+// fieldPlan is how to fill a single struct field
+type fieldPlan struct {
+	// Index of the field in the struct
+	index int
+	// Name of the field in the struct
+	name string
+	// Full splunk label of the field, including the prefix of any outer structs
+	label string
+	tag   fieldTag
+	// The field has a "splunk" tag
+	tagged bool
+
+	// For nested and embedded structs, the plan of the struct
+	nested     *structPlan
+	nestedType reflect.Type
+	pointer    bool
+}
+
+// planCache holds the *structPlan of each struct type
+var planCache sync.Map
+
+// getStructPlan Returns the cached plan for the struct type, building it if needed
+func getStructPlan(typeOf reflect.Type) *structPlan {
+	if plan, ok := planCache.Load(typeOf); ok {
+		return plan.(*structPlan)
+	}
+	plan := buildStructPlan(typeOf, "", map[reflect.Type]bool{})
+	actual, _ := planCache.LoadOrStore(typeOf, plan)
+	return actual.(*structPlan)
+}
+
+// buildStructPlan Builds the plan of the struct type, adding the prefix to each label.
+// Visiting holds the struct types being built to stop recursive types.
+func buildStructPlan(typeOf reflect.Type, prefix string, visiting map[reflect.Type]bool) *structPlan {
+	visiting[typeOf] = true
+	defer delete(visiting, typeOf)
 
+	plan := &structPlan{}
 	for i := 0; i < typeOf.NumField(); i++ {
 		thisField := typeOf.Field(i)
-		label := thisField.Name
-		if splunkLabel := typeOf.Field(i).Tag.Get("splunk"); splunkLabel != "" {
-			label = splunkLabel
-		}
-
-		// Find this field in our SearchResult
-		for key, value := range e {
-			if key == label {
-				// We found this field!  Set it in the struct
-				switch thisField.Type.Kind() {
-				case reflect.String:
-					valueOf.Field(i).SetString(fmt.Sprintf("%v", value))
-				case reflect.TypeOf(time.Time{}).Kind():
-					// Parse the time and set it
-					parsedTime, err := ParseTime(fmt.Sprintf("%v", value))
-					if err != nil {
-						return fmt.Errorf("Could not parse a time field from splunk that we want to fill as time in the struct.  splunk field: %s, struct field: %s, value: %v", key, thisField.Name, value)
-					}
-					valueOf.Field(i).Set(reflect.ValueOf(parsedTime))
-				case reflect.Int, reflect.Int16, reflect.Int32, reflect.Int64:
-					floatValue, err := getFloatValue(value)
-					if err != nil {
-						return fmt.Errorf("Could not parse a number field from splunk that we want to fill as a number in the struct.  splunk field: %s, struct field: %s, value: %v", key, thisField.Name, value)
-					}
-					valueOf.Field(i).SetInt(int64(floatValue))
-				case reflect.Float32, reflect.Float64:
-					floatValue, err := getFloatValue(value)
-					if err != nil {
-						return fmt.Errorf("Could not parse a number field from splunk that we want to fill as a number in the struct.  splunk field: %s, struct field: %s, value: %v", key, thisField.Name, value)
-					}
-					valueOf.Field(i).SetFloat(floatValue)
-				case reflect.Interface:
-					valueOf.Field(i).Set(reflect.ValueOf(value))
+		if thisField.PkgPath != "" && !thisField.Anonymous {
+			// Unexported
+			continue
+		}
+		tag := parseFieldTag(thisField)
+		if tag.skip {
+			continue
+		}
+		field := &fieldPlan{
+			index:  i,
+			name:   thisField.Name,
+			label:  prefix + tag.name,
+			tag:    tag,
+			tagged: thisField.Tag.Get("splunk") != "",
+		}
+
+		// Nested structs are filled from the fields starting with "label.", and the fields of
+		// embedded structs are promoted unless the embedded struct is tagged
+		if structType := nestedStructType(thisField.Type); structType != nil {
+			if visiting[structType] {
+				continue
+			}
+			nestedPrefix := field.label + "."
+			if thisField.Anonymous && !field.tagged {
+				nestedPrefix = prefix
+			}
+			field.nestedType = structType
+			field.pointer = thisField.Type.Kind() == reflect.Ptr
+			if field.pointer && thisField.PkgPath != "" {
+				// Can not allocate an unexported embedded pointer
+				continue
+			}
+			field.nested = buildStructPlan(structType, nestedPrefix, visiting)
+			plan.fields = append(plan.fields, field)
+			continue
+		}
+		if thisField.PkgPath != "" {
+			// Unexported embedded type that is not a struct
+			continue
+		}
+
+		plan.fields = append(plan.fields, field)
+	}
+	return plan
+}
+
+// unmarshalStruct Fills in the fields of the struct value following the plan.
+// It returns true if any field was found in the result.
+func (state *decodeState) unmarshalStruct(valueOf reflect.Value, plan *structPlan) bool {
+	found := false
+
+	for _, field := range plan.fields {
+		fieldValue := valueOf.Field(field.index)
+
+		if field.nested != nil {
+			nestedValue := fieldValue
+			if field.pointer {
+				// Only set the pointer if something is found
+				nestedValue = reflect.New(field.nestedType).Elem()
+				if !fieldValue.IsNil() {
+					nestedValue = fieldValue.Elem()
 				}
 			}
+			errorCount := len(state.errors)
+			nestedFound := state.unmarshalStruct(nestedValue, field.nested)
+			if nestedFound {
+				found = true
+				if field.pointer && fieldValue.IsNil() {
+					fieldValue.Set(nestedValue.Addr())
+				}
+			} else if field.pointer {
+				// The whole struct is missing, so its fields are not required
+				state.errors = state.errors[:errorCount]
+			}
+			continue
+		}
+
+		value, ok := state.result[field.label]
+		if !ok {
+			if field.tag.required || (state.decoder.RequireTaggedFields && field.tagged) {
+				state.errors = append(state.errors, &FieldError{SplunkField: field.label, StructField: field.name, Err: ErrMissingField})
+			}
+			continue
+		}
+
+		// We found this field!  Set it in the struct
+		found = true
+		if state.used != nil {
+			state.used[field.label] = true
+		}
+		if err := setValue(fieldValue, value, field.tag); err != nil {
+			state.errors = append(state.errors, &FieldError{SplunkField: field.label, StructField: field.name, Value: value, Err: err})
+		}
+	}
+	return found
+}
+
+// nestedStructType Returns the struct type if the field type is a struct or pointer to a struct filled field by field, or nil
+func nestedStructType(fieldType reflect.Type) reflect.Type {
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	if fieldType.Kind() != reflect.Struct || fieldType == timeType {
+		return nil
+	}
+	// Structs that decode themselves are a single field
+	pointerType := reflect.PtrTo(fieldType)
+	if pointerType.Implements(unmarshalerType) || pointerType.Implements(textUnmarshalerType) {
+		return nil
+	}
+	return fieldType
+}
+
+// setValue Sets the field to the splunk value, converting the value to the type of the field.
+//
+// Multi-value fields from splunk are JSON arrays.  They fill slice fields element by element,
+// or are joined into string fields.
+func setValue(field reflect.Value, value interface{}, tag fieldTag) error {
+	multiValue, isMultiValue := value.([]interface{})
+
+	if field.Kind() == reflect.Ptr {
+		if value == nil {
+			return nil
+		}
+		elem := reflect.New(field.Type().Elem())
+		if err := setValue(elem.Elem(), value, tag); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+
+	// Types that decode themselves
+	if field.CanAddr() {
+		if unmarshaler, ok := field.Addr().Interface().(Unmarshaler); ok {
+			return unmarshaler.UnmarshalSplunk(value)
+		}
+		if textUnmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok && field.Type() != timeType {
+			if value == nil {
+				return nil
+			}
+			text := fmt.Sprintf("%v", value)
+			if isMultiValue {
+				parts := make([]string, 0, len(multiValue))
+				for _, element := range multiValue {
+					parts = append(parts, fmt.Sprintf("%v", element))
+				}
+				text = strings.Join(parts, tag.join)
+			}
+			if err := textUnmarshaler.UnmarshalText([]byte(text)); err != nil {
+				return fmt.Errorf("Could not parse a field from splunk with the UnmarshalText of the struct field: %s", err)
+			}
+			return nil
+		}
+	}
+
+	if field.Kind() == reflect.Slice {
+		values := multiValue
+		if !isMultiValue {
+			stringValue, isString := value.(string)
+			switch {
+			case value == nil:
+				values = nil
+			case tag.split != "" && isString:
+				for _, part := range strings.Split(stringValue, tag.split) {
+					values = append(values, part)
+				}
+			default:
+				// A single value
+				values = []interface{}{value}
+			}
+		}
+
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, element := range values {
+			if err := setValue(slice.Index(i), element, tag); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	if isMultiValue && field.Kind() != reflect.Interface {
+		if field.Kind() != reflect.String {
+			return fmt.Errorf("Could not fill a multi-value field from splunk into a single value in the struct, use a slice")
+		}
+		parts := make([]string, 0, len(multiValue))
+		for _, element := range multiValue {
+			parts = append(parts, fmt.Sprintf("%v", element))
+		}
+		field.SetString(strings.Join(parts, tag.join))
+		return nil
+	}
+
+	switch field.Type() {
+	case timeType:
+		// Parse the time and set it
+		var parsedTime time.Time
+		var err error
+		if tag.timeFormat != "" {
+			parsedTime, err = parseTimeFormat(value, tag.timeFormat)
+		} else {
+			parsedTime, err = ParseAnyTime(value)
+		}
+		if err != nil {
+			return fmt.Errorf("Could not parse a time field from splunk that we want to fill as time in the struct")
+		}
+		field.Set(reflect.ValueOf(parsedTime))
+		return nil
+	case durationType:
+		// Splunk durations are in seconds
+		if seconds, err := getFloatValue(value); err == nil {
+			field.SetInt(int64(seconds * float64(time.Second)))
+			return nil
+		}
+		duration, err := time.ParseDuration(fmt.Sprintf("%v", value))
+		if err != nil {
+			return fmt.Errorf("Could not parse a duration field from splunk that we want to fill as a duration in the struct")
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(fmt.Sprintf("%v", value))
+	case reflect.Bool:
+		boolValue, err := getBoolValue(value)
+		if err != nil {
+			return fmt.Errorf("Could not parse a boolean field from splunk that we want to fill as a boolean in the struct")
+		}
+		field.SetBool(boolValue)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		floatValue, err := getFloatValue(value)
+		if err != nil || floatValue < 0 {
+			return fmt.Errorf("Could not parse a number field from splunk that we want to fill as an unsigned number in the struct")
+		}
+		field.SetUint(uint64(floatValue))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		floatValue, err := getFloatValue(value)
+		if err != nil {
+			return fmt.Errorf("Could not parse a number field from splunk that we want to fill as a number in the struct")
+		}
+		field.SetInt(int64(floatValue))
+	case reflect.Float32, reflect.Float64:
+		floatValue, err := getFloatValue(value)
+		if err != nil {
+			return fmt.Errorf("Could not parse a number field from splunk that we want to fill as a number in the struct")
+		}
+		field.SetFloat(floatValue)
+	case reflect.Interface:
+		if value != nil {
+			field.Set(reflect.ValueOf(value))
 		}
 	}
 	return nil
 }
 
-// getFloatValue will check if the interface is a float64, or we can parse it, or return an error
+// getFloatValue will check if the interface is a number, or we can parse it, or return an error
 func getFloatValue(i interface{}) (float64, error) {
 	floatValue, ok := i.(float64)
 	if ok {
 		return floatValue, nil
 	}
 
+	// Go numbers, such as the ints of an event from MarshalEvent
+	valueOf := reflect.ValueOf(i)
+	switch valueOf.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(valueOf.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(valueOf.Uint()), nil
+	case reflect.Float32:
+		return valueOf.Float(), nil
+	}
+
 	// Try parsing it as a string
 	if stringValue, ok := i.(string); ok {
 		floatValue, err := strconv.ParseFloat(stringValue, 64)
@@ -76,3 +461,108 @@ func getFloatValue(i interface{}) (float64, error) {
 	return 0, fmt.Errorf("Could not parse as number")
 
 }
+
+// getBoolValue will check if the interface is a bool, or we can parse it, or return an error
+func getBoolValue(i interface{}) (bool, error) {
+	switch value := i.(type) {
+	case bool:
+		return value, nil
+	case float64:
+		return value != 0, nil
+	case string:
+		return strconv.ParseBool(value)
+	}
+	return false, fmt.Errorf("Could not parse as boolean")
+}
+
+// MarshalEvent Converts a struct into an event to send to splunk.  The passed in interface must be a struct or a pointer to a struct
+//
+// Each field is named with the "splunk" tag on the field, or the field name if there is no tag.  The tag can have options after the name:
+//   - omitempty: skip the field if it is the zero value
+//   - indexed: send the field as an indexed field instead of in the event
+//
+// Nested structs are flattened into fields starting with "label.", such as "src.ip" for a struct field tagged "src",
+// and the fields of embedded structs are written as if they were in the outer struct, the same as UnMarshal reads them.
+//
+// A time.Time field named "_time" is used as the event time.  Other time.Time fields are formatted with FormatTime,
+// or with the layout from a `timeformat=` tag option.
+// Fields tagged with "-" and unexported fields are skipped.
+//
+// The event can be sent with a HECClient, or the JSON of its Event sent to the receivers.
+func MarshalEvent(v interface{}) (*HECEvent, error) {
+	valueOf := reflect.ValueOf(v)
+	if valueOf.Kind() == reflect.Ptr {
+		if valueOf.IsNil() {
+			return nil, fmt.Errorf("cannot marshal a nil pointer")
+		}
+		valueOf = valueOf.Elem()
+	}
+	if valueOf.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot marshal a %s, must be a struct", valueOf.Kind())
+	}
+
+	fields := map[string]interface{}{}
+	event := &HECEvent{}
+	marshalStruct(valueOf, getStructPlan(valueOf.Type()), event, fields)
+	event.Event = fields
+
+	return event, nil
+}
+
+// marshalStruct Adds the fields of the struct value following the plan to the event fields, or to the indexed fields of the event
+func marshalStruct(valueOf reflect.Value, plan *structPlan, event *HECEvent, fields map[string]interface{}) {
+	for _, field := range plan.fields {
+		fieldValue := valueOf.Field(field.index)
+		if !fieldValue.CanInterface() {
+			// Exported field of an unexported embedded struct
+			continue
+		}
+		if field.tag.omitEmpty && fieldValue.IsZero() {
+			continue
+		}
+
+		if field.nested != nil {
+			if field.pointer {
+				if fieldValue.IsNil() {
+					continue
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			marshalStruct(fieldValue, field.nested, event, fields)
+			continue
+		}
+
+		if fieldValue.Kind() == reflect.Ptr {
+			if fieldValue.IsNil() {
+				if !field.tag.indexed {
+					fields[field.label] = nil
+				}
+				continue
+			}
+			fieldValue = fieldValue.Elem()
+		}
+
+		value := fieldValue.Interface()
+		if timeValue, ok := value.(time.Time); ok {
+			if field.label == eventTimeLabel {
+				event.Time = timeValue
+				continue
+			}
+			if field.tag.timeFormat != "" {
+				value = formatTimeFormat(timeValue, field.tag.timeFormat)
+			} else {
+				value = FormatTime(timeValue)
+			}
+		}
+
+		if field.tag.indexed {
+			if event.Fields == nil {
+				event.Fields = map[string]interface{}{}
+			}
+			// Indexed fields must be strings
+			event.Fields[field.label] = fmt.Sprintf("%v", value)
+			continue
+		}
+		fields[field.label] = value
+	}
+}
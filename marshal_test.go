@@ -2,8 +2,11 @@ package splunk
 
 import (
 	"fmt"
+	"net"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestMarshal(t *testing.T) {
@@ -28,3 +31,328 @@ func TestMarshal(t *testing.T) {
 	}
 	fmt.Println(testStruct)
 }
+
+func TestMarshalEvent(t *testing.T) {
+	type TestStruct struct {
+		Time     time.Time `splunk:"_time"`
+		String   string    `splunk:"string"`
+		Empty    string    `splunk:"empty,omitempty"`
+		Int      int       `splunk:"int"`
+		Pointer  *float64  `splunk:"pointer,omitempty"`
+		Env      string    `splunk:"env,indexed"`
+		Created  time.Time `splunk:"created"`
+		Skipped  string    `splunk:"-"`
+		Untagged bool
+		private  string
+	}
+
+	pointer := 1.5
+	event, err := MarshalEvent(&TestStruct{
+		Time:     time.Unix(1600000000, 0),
+		String:   "stringVal",
+		Int:      3,
+		Pointer:  &pointer,
+		Env:      "prod",
+		Created:  time.Date(2006, 1, 2, 15, 4, 0, 0, time.UTC),
+		Skipped:  "skipped",
+		Untagged: true,
+		private:  "private",
+	})
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1600000000, 0), event.Time)
+	require.Equal(t, map[string]interface{}{"env": "prod"}, event.Fields)
+	require.Equal(t, map[string]interface{}{
+		"string":   "stringVal",
+		"int":      3,
+		"pointer":  1.5,
+		"created":  "2006-01-02T15:04:00.000+00:00",
+		"Untagged": true,
+	}, event.Event)
+
+	_, err = MarshalEvent("not a struct")
+	require.Error(t, err)
+}
+
+func TestMarshalEventRoundTrip(t *testing.T) {
+	type Common struct {
+		Host string `splunk:"host"`
+	}
+	type Endpoint struct {
+		IP   string `splunk:"ip"`
+		Port uint16 `splunk:"port"`
+	}
+	type TestStruct struct {
+		Common
+		String string    `splunk:"string,omitempty"`
+		Float  float64   `splunk:"float"`
+		Int    int       `splunk:"int"`
+		Src    Endpoint  `splunk:"src"`
+		Dest   *Endpoint `splunk:"dest"`
+	}
+
+	original := TestStruct{
+		Common: Common{Host: "web01"},
+		String: "stringVal",
+		Float:  2.5,
+		Int:    3,
+		Src:    Endpoint{IP: "10.0.0.1", Port: 443},
+		Dest:   &Endpoint{IP: "10.0.0.2", Port: 8080},
+	}
+	event, err := MarshalEvent(original)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"host":      "web01",
+		"string":    "stringVal",
+		"float":     2.5,
+		"int":       3,
+		"src.ip":    "10.0.0.1",
+		"src.port":  uint16(443),
+		"dest.ip":   "10.0.0.2",
+		"dest.port": uint16(8080),
+	}, event.Event)
+
+	testStruct := TestStruct{}
+	require.NoError(t, SearchResult(event.Event.(map[string]interface{})).UnMarshal(&testStruct))
+	require.Equal(t, original, testStruct)
+
+	// A nil nested pointer is left out
+	event, err = MarshalEvent(TestStruct{})
+	require.NoError(t, err)
+	require.NotContains(t, event.Event, "dest.ip")
+}
+
+func TestUnMarshalMultiValue(t *testing.T) {
+	type TestStruct struct {
+		Hosts     []string  `splunk:"hosts"`
+		Ports     []int     `splunk:"ports"`
+		Single    []string  `splunk:"single"`
+		Split     []string  `splunk:"split,split=comma"`
+		Joined    string    `splunk:"joined,join=comma"`
+		Newlines  string    `splunk:"newlines"`
+		Values    []float64 `splunk:"values"`
+		Interface interface{}
+	}
+
+	searchResult := SearchResult{
+		"hosts":     []interface{}{"web01", "web02"},
+		"ports":     []interface{}{"80", float64(443)},
+		"single":    "web03",
+		"split":     "a,b,c",
+		"joined":    []interface{}{"a", "b"},
+		"newlines":  []interface{}{"a", "b"},
+		"values":    []interface{}{"1.5"},
+		"Interface": []interface{}{"x"},
+	}
+
+	testStruct := TestStruct{}
+	require.NoError(t, searchResult.UnMarshal(&testStruct))
+	require.Equal(t, TestStruct{
+		Hosts:     []string{"web01", "web02"},
+		Ports:     []int{80, 443},
+		Single:    []string{"web03"},
+		Split:     []string{"a", "b", "c"},
+		Joined:    "a,b",
+		Newlines:  "a\nb",
+		Values:    []float64{1.5},
+		Interface: []interface{}{"x"},
+	}, testStruct)
+
+	// Multi-value into a single number is an error
+	type BadStruct struct {
+		Count int `splunk:"count"`
+	}
+	err := SearchResult{"count": []interface{}{"1", "2"}}.UnMarshal(&BadStruct{})
+	require.Error(t, err)
+
+	// Bad element
+	err = SearchResult{"ports": []interface{}{"80", "http"}}.UnMarshal(&TestStruct{})
+	require.Error(t, err)
+}
+
+func TestUnMarshalTypes(t *testing.T) {
+	type Status string
+	type Endpoint struct {
+		IP   string `splunk:"ip"`
+		Port uint16 `splunk:"port"`
+	}
+	type Common struct {
+		Host string `splunk:"host"`
+	}
+	type TestStruct struct {
+		Common
+		Enabled  bool          `splunk:"enabled"`
+		Flag     bool          `splunk:"flag"`
+		Count    uint          `splunk:"count"`
+		Small    int8          `splunk:"small"`
+		Duration time.Duration `splunk:"duration"`
+		Timeout  time.Duration `splunk:"timeout"`
+		Status   Status        `splunk:"status"`
+		Present  *int          `splunk:"present"`
+		Absent   *int          `splunk:"absent"`
+		Src      Endpoint      `splunk:"src"`
+		Dest     *Endpoint     `splunk:"dest"`
+		Other    *Endpoint     `splunk:"other"`
+	}
+
+	searchResult := SearchResult{
+		"host":     "web01",
+		"enabled":  "true",
+		"flag":     float64(0),
+		"count":    "12",
+		"small":    float64(-3),
+		"duration": "1.5",
+		"timeout":  "2m",
+		"status":   "open",
+		"present":  "7",
+		"src.ip":   "10.0.0.1",
+		"src.port": "443",
+		"dest.ip":  "10.0.0.2",
+	}
+
+	testStruct := TestStruct{}
+	require.NoError(t, searchResult.UnMarshal(&testStruct))
+	present := 7
+	require.Equal(t, TestStruct{
+		Common:   Common{Host: "web01"},
+		Enabled:  true,
+		Flag:     false,
+		Count:    12,
+		Small:    -3,
+		Duration: time.Millisecond * 1500,
+		Timeout:  time.Minute * 2,
+		Status:   "open",
+		Present:  &present,
+		Src:      Endpoint{IP: "10.0.0.1", Port: 443},
+		Dest:     &Endpoint{IP: "10.0.0.2"},
+	}, testStruct)
+	require.Nil(t, testStruct.Absent)
+	require.Nil(t, testStruct.Other)
+
+	require.Error(t, SearchResult{"count": "-1"}.UnMarshal(&TestStruct{}))
+	require.Error(t, SearchResult{"enabled": "maybe"}.UnMarshal(&TestStruct{}))
+}
+
+// testSeverity is an enum that decodes itself from a splunk field
+type testSeverity int
+
+func (s *testSeverity) UnmarshalSplunk(value interface{}) error {
+	switch value {
+	case "low":
+		*s = 1
+	case "high":
+		*s = 2
+	default:
+		return fmt.Errorf("unknown severity: %v", value)
+	}
+	return nil
+}
+
+// testEpoch is a timestamp that decodes itself from epoch seconds
+type testEpoch struct {
+	time.Time
+}
+
+func (e *testEpoch) UnmarshalSplunk(value interface{}) error {
+	seconds, err := getFloatValue(value)
+	if err != nil {
+		return err
+	}
+	e.Time = time.Unix(int64(seconds), 0).UTC()
+	return nil
+}
+
+func TestUnMarshalCustom(t *testing.T) {
+	type TestStruct struct {
+		Severity   testSeverity   `splunk:"severity"`
+		Severities []testSeverity `splunk:"severities"`
+		Seen       testEpoch      `splunk:"seen"`
+		IP         net.IP         `splunk:"ip"`
+		Dest       *net.IP        `splunk:"dest"`
+	}
+
+	searchResult := SearchResult{
+		"severity":   "high",
+		"severities": []interface{}{"low", "high"},
+		"seen":       "1600000000",
+		"ip":         "10.0.0.1",
+		"dest":       "10.0.0.2",
+	}
+
+	testStruct := TestStruct{}
+	require.NoError(t, searchResult.UnMarshal(&testStruct))
+	dest := net.ParseIP("10.0.0.2")
+	require.Equal(t, TestStruct{
+		Severity:   2,
+		Severities: []testSeverity{1, 2},
+		Seen:       testEpoch{time.Unix(1600000000, 0).UTC()},
+		IP:         net.ParseIP("10.0.0.1"),
+		Dest:       &dest,
+	}, testStruct)
+
+	require.Error(t, SearchResult{"severity": "medium"}.UnMarshal(&TestStruct{}))
+	require.Error(t, SearchResult{"ip": "not an ip"}.UnMarshal(&TestStruct{}))
+}
+
+func TestUnMarshalRecursive(t *testing.T) {
+	type Node struct {
+		Name   string `splunk:"name"`
+		Parent *Node  `splunk:"parent"`
+	}
+
+	node := Node{}
+	require.NoError(t, SearchResult{"name": "child"}.UnMarshal(&node))
+	require.Equal(t, Node{Name: "child"}, node)
+}
+
+// benchmarkResult is a typical row from a search
+var benchmarkResult = SearchResult{
+	"_time":         "2020-09-13T12:26:40.000+00:00",
+	"_raw":          "GET /index.html 200 512 0.012",
+	"host":          "web01",
+	"source":        "/var/log/access.log",
+	"sourcetype":    "access_combined",
+	"method":        "GET",
+	"uri":           "/index.html",
+	"status":        "200",
+	"bytes":         "512",
+	"duration":      "0.012",
+	"user_agent":    "Mozilla/5.0",
+	"clientip":      "10.0.0.1",
+	"splunk_server": "idx01",
+}
+
+type benchmarkStruct struct {
+	Time       time.Time     `splunk:"_time"`
+	Host       string        `splunk:"host"`
+	Source     string        `splunk:"source"`
+	SourceType string        `splunk:"sourcetype"`
+	Method     string        `splunk:"method"`
+	URI        string        `splunk:"uri"`
+	Status     int           `splunk:"status"`
+	Bytes      int64         `splunk:"bytes"`
+	Duration   time.Duration `splunk:"duration"`
+	UserAgent  string        `splunk:"user_agent"`
+	ClientIP   net.IP        `splunk:"clientip"`
+}
+
+func BenchmarkUnMarshal(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		result := benchmarkStruct{}
+		if err := benchmarkResult.UnMarshal(&result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecoderStrict(b *testing.B) {
+	decoder := &Decoder{RequireTaggedFields: true, DisallowUnknownFields: true}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		result := benchmarkStruct{}
+		err := decoder.Decode(benchmarkResult, &result)
+		if err == nil {
+			b.Fatal("expected unknown field error for splunk_server")
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package splunk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const searchParserSuffix = "/services/search/parser"
+
+// ParsedCommand is a single command of a search parsed by splunk
+type ParsedCommand struct {
+	Command string `json:"command"`
+	// The arguments as written
+	RawArgs string `json:"rawargs"`
+	// Where the command runs, such as "streaming" or "report"
+	Pipeline     string `json:"pipeline"`
+	IsGenerating bool   `json:"isGenerating"`
+	StreamType   string `json:"streamType"`
+	// The parsed arguments, their layout depends on the command
+	Args interface{} `json:"args"`
+}
+
+// SearchMessage is a message from splunk about a search, such as an error or warning
+type SearchMessage struct {
+	// Level of the message, such as "FATAL", "ERROR", "WARN" or "INFO"
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// ParsedSearch is a search as splunk would run it
+type ParsedSearch struct {
+	Commands []ParsedCommand `json:"commands"`
+	// The full search with macros expanded, as it runs
+	NormalizedSearch string `json:"normalizedSearch"`
+	// The part of the search run on the indexers
+	RemoteSearch string `json:"remoteSearch"`
+	// The part of the search run on the search head
+	ReportsSearch string `json:"reportsSearch"`
+	// Warnings about the search
+	Messages []SearchMessage `json:"messages"`
+}
+
+// SearchParseError is returned when splunk can not parse a search
+type SearchParseError struct {
+	Messages []SearchMessage
+}
+
+func (e *SearchParseError) Error() string {
+	texts := make([]string, len(e.Messages))
+	for i, message := range e.Messages {
+		texts[i] = message.Text
+	}
+	return fmt.Sprintf("invalid search: %s", strings.Join(texts, "; "))
+}
+
+// ParseSearch Asks splunk to parse the query without running it, to validate it before creating a job.
+//
+// The query is parsed as CreateSearchJob would run it.  If splunk rejects it the error is a *SearchParseError with its messages.
+// Params are any other parameters you want to specific from [the documentation](https://docs.splunk.com/Documentation/Splunk/8.0.5/RESTREF/RESTsearch#search.2Fparser),
+// such as "parse_only" to skip expanding macros.
+func (c *Client) ParseSearch(ctx context.Context, query string, params map[string]string) (*ParsedSearch, error) {
+	paramsToSend := map[string]string{}
+	for key, value := range params {
+		paramsToSend[key] = value
+	}
+	paramsToSend["q"] = fmt.Sprintf("search %s", query)
+
+	resp, err := c.BuildResponse(ctx, http.MethodGet, searchParserSuffix, paramsToSend)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := &ParsedSearch{}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if err := json.Unmarshal(body, parsed); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal: %s, body: %s", err, string(body))
+		}
+		return parsed, nil
+	case http.StatusBadRequest:
+		if err := json.Unmarshal(body, parsed); err == nil && len(parsed.Messages) > 0 {
+			return nil, &SearchParseError{Messages: parsed.Messages}
+		}
+	}
+	return nil, fmt.Errorf("bad status code: %d, body: %s", resp.StatusCode, string(body))
+}
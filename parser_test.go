@@ -0,0 +1,67 @@
+package splunk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ParseSearch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method != http.MethodGet:
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		case req.URL.Path != searchParserSuffix:
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		values := req.URL.Query()
+		if values.Get("output_mode") != "json" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		switch values.Get("q") {
+		case "search index=main | stats count":
+			rw.Write([]byte(`{
+				"remoteSearch": "litsearch index=main | prestats count",
+				"reportsSearch": "stats count",
+				"normalizedSearch": "litsearch index=main | stats count",
+				"commands": [
+					{"command": "search", "rawargs": "index=main", "pipeline": "streaming", "args": {"search": ["index=main"]}, "isGenerating": true, "streamType": "SP_STREAM"},
+					{"command": "stats", "rawargs": "count", "pipeline": "report", "args": "count", "isGenerating": false, "streamType": "SP_EVENTS"}
+				],
+				"messages": []
+			}`))
+		default:
+			rw.WriteHeader(http.StatusBadRequest)
+			rw.Write([]byte(`{"messages":[{"type":"FATAL","text":"Error in 'stats' command: The argument 'cnt' is invalid."}]}`))
+		}
+	}))
+	defer server.Close()
+	client := &Client{
+		config: &Config{
+			BaseURL:    server.URL,
+			HTTPClient: http.DefaultClient,
+		},
+	}
+
+	parsed, err := client.ParseSearch(context.Background(), "index=main | stats count", nil)
+	require.NoError(t, err)
+	require.Equal(t, "litsearch index=main | stats count", parsed.NormalizedSearch)
+	require.Len(t, parsed.Commands, 2)
+	require.Equal(t, "stats", parsed.Commands[1].Command)
+	require.Equal(t, "report", parsed.Commands[1].Pipeline)
+	require.True(t, parsed.Commands[0].IsGenerating)
+
+	_, err = client.ParseSearch(context.Background(), "index=main | stats cnt", nil)
+	parseErr := &SearchParseError{}
+	require.True(t, errors.As(err, &parseErr))
+	require.Equal(t, "FATAL", parseErr.Messages[0].Type)
+	require.Contains(t, err.Error(), "The argument 'cnt' is invalid")
+}
@@ -0,0 +1,225 @@
+package splunk
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Raw is SPL inserted into a query as is.  Never build one from user input
+type Raw string
+
+// FieldName is a field name inserted into a query.  It must be a plain name, such as "host" or "data.user_id"
+type FieldName string
+
+var (
+	fieldNameRegexp   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+	placeholderRegexp = regexp.MustCompile(`\$([A-Za-z_][A-Za-z0-9_]*)\$`)
+)
+
+// QuoteString Returns the value as a double quoted SPL string, escaping backslashes and quotes.
+//
+// A quoted value is always a single term, but in the search command an asterisk in it is still a wildcard.
+func QuoteString(value string) string {
+	escaped := strings.Replace(value, `\`, `\\`, -1)
+	escaped = strings.Replace(escaped, `"`, `\"`, -1)
+	return `"` + escaped + `"`
+}
+
+// FormatValue Formats a value to insert in a query.
+//
+// Strings are quoted, numbers and bools are written as is, times are written as epoch seconds,
+// FieldName is checked to be a plain field name, Raw is inserted as is, and slices become a comma separated list.
+func FormatValue(value interface{}) (string, error) {
+	switch typedValue := value.(type) {
+	case Raw:
+		return string(typedValue), nil
+	case FieldName:
+		if !fieldNameRegexp.MatchString(string(typedValue)) {
+			return "", fmt.Errorf("bad field name: %q", typedValue)
+		}
+		return string(typedValue), nil
+	case string:
+		return QuoteString(typedValue), nil
+	case time.Time:
+		return formatEpoch(typedValue), nil
+	case fmt.Stringer:
+		return QuoteString(typedValue.String()), nil
+	case bool:
+		return strconv.FormatBool(typedValue), nil
+	case int:
+		return strconv.FormatInt(int64(typedValue), 10), nil
+	case int64:
+		return strconv.FormatInt(typedValue, 10), nil
+	case int32:
+		return strconv.FormatInt(int64(typedValue), 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(typedValue), 10), nil
+	case uint64:
+		return strconv.FormatUint(typedValue, 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(typedValue), 10), nil
+	case float64:
+		return strconv.FormatFloat(typedValue, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(typedValue), 'f', -1, 32), nil
+	case []string:
+		values := make([]interface{}, len(typedValue))
+		for i, value := range typedValue {
+			values[i] = value
+		}
+		return formatList(values)
+	case []interface{}:
+		return formatList(typedValue)
+	}
+	return "", fmt.Errorf("unsupported query value type %T", value)
+}
+
+// formatList Formats each value of a list and joins them with commas
+func formatList(values []interface{}) (string, error) {
+	formatted := make([]string, len(values))
+	for i, value := range values {
+		formattedValue, err := FormatValue(value)
+		if err != nil {
+			return "", err
+		}
+		formatted[i] = formattedValue
+	}
+	return strings.Join(formatted, ","), nil
+}
+
+// BindQuery Replaces each $name$ placeholder in the template with its parameter formatted by FormatValue,
+// ex: BindQuery(`index=main user=$user$ | head $count$`, map[string]interface{}{"user": user, "count": 10}).
+//
+// A $ that is not part of a placeholder is left as is.  It returns an error if a placeholder has no parameter.
+func BindQuery(template string, params map[string]interface{}) (string, error) {
+	var bindErr error
+	query := placeholderRegexp.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := strings.Trim(placeholder, "$")
+		value, ok := params[name]
+		if !ok {
+			if bindErr == nil {
+				bindErr = fmt.Errorf("missing query parameter: %s", name)
+			}
+			return placeholder
+		}
+		formatted, err := FormatValue(value)
+		if err != nil && bindErr == nil {
+			bindErr = fmt.Errorf("bad query parameter %s: %s", name, err)
+		}
+		return formatted
+	})
+	if bindErr != nil {
+		return "", bindErr
+	}
+	return query, nil
+}
+
+// QueryBuilder builds a query from search terms followed by a pipeline of commands, escaping every value.
+//
+// The first error is kept and returned by Build, so calls can be chained.
+type QueryBuilder struct {
+	terms    []string
+	commands []string
+	err      error
+}
+
+// NewQuery Creates an empty query builder
+func NewQuery() *QueryBuilder {
+	return &QueryBuilder{}
+}
+
+// Index Searches the indexes, ex: index="main"
+func (q *QueryBuilder) Index(indexes ...string) *QueryBuilder {
+	if len(indexes) == 0 {
+		return q.fail(fmt.Errorf("no index"))
+	}
+	terms := make([]string, len(indexes))
+	for i, index := range indexes {
+		terms[i] = "index=" + QuoteString(index)
+	}
+	return q.orTerms(terms)
+}
+
+// Where Matches events where the field has the value, ex: host="web-1"
+func (q *QueryBuilder) Where(field string, value interface{}) *QueryBuilder {
+	return q.Term("$field$=$value$", map[string]interface{}{"field": FieldName(field), "value": value})
+}
+
+// WhereNot Matches events where the field does not have the value, ex: NOT host="web-1"
+func (q *QueryBuilder) WhereNot(field string, value interface{}) *QueryBuilder {
+	return q.Term("NOT $field$=$value$", map[string]interface{}{"field": FieldName(field), "value": value})
+}
+
+// WhereIn Matches events where the field has any of the values, ex: host IN ("web-1","web-2")
+func (q *QueryBuilder) WhereIn(field string, values ...interface{}) *QueryBuilder {
+	if len(values) == 0 {
+		return q.fail(fmt.Errorf("no values for %s", field))
+	}
+	return q.Term("$field$ IN ($values$)", map[string]interface{}{"field": FieldName(field), "values": values})
+}
+
+// Text Matches events containing each phrase
+func (q *QueryBuilder) Text(phrases ...string) *QueryBuilder {
+	for _, phrase := range phrases {
+		q.terms = append(q.terms, QuoteString(phrase))
+	}
+	return q
+}
+
+// Term Adds a search term from a template bound with BindQuery, ex: Term("bytes>$min$", map[string]interface{}{"min": 100})
+func (q *QueryBuilder) Term(template string, params map[string]interface{}) *QueryBuilder {
+	term, err := BindQuery(template, params)
+	if err != nil {
+		return q.fail(err)
+	}
+	q.terms = append(q.terms, term)
+	return q
+}
+
+// Pipe Adds a command to the pipeline from a template bound with BindQuery, ex: Pipe("head $count$", map[string]interface{}{"count": 10})
+func (q *QueryBuilder) Pipe(template string, params map[string]interface{}) *QueryBuilder {
+	command, err := BindQuery(template, params)
+	if err != nil {
+		return q.fail(err)
+	}
+	command = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(command), "|"))
+	if command == "" {
+		return q.fail(fmt.Errorf("empty command"))
+	}
+	q.commands = append(q.commands, command)
+	return q
+}
+
+// Build Returns the query to pass to CreateSearchJob, or the first error from building it.
+// A query without search terms matches all events, "*".
+func (q *QueryBuilder) Build() (string, error) {
+	if q.err != nil {
+		return "", q.err
+	}
+	terms := "*"
+	if len(q.terms) > 0 {
+		terms = strings.Join(q.terms, " ")
+	}
+	return strings.Join(append([]string{terms}, q.commands...), " | "), nil
+}
+
+// orTerms Adds the terms joined with OR
+func (q *QueryBuilder) orTerms(terms []string) *QueryBuilder {
+	if len(terms) == 1 {
+		q.terms = append(q.terms, terms[0])
+		return q
+	}
+	q.terms = append(q.terms, "("+strings.Join(terms, " OR ")+")")
+	return q
+}
+
+// fail Records the first error
+func (q *QueryBuilder) fail(err error) *QueryBuilder {
+	if q.err == nil {
+		q.err = err
+	}
+	return q
+}
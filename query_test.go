@@ -0,0 +1,64 @@
+package splunk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteString(t *testing.T) {
+	require.Equal(t, `"simple"`, QuoteString("simple"))
+	require.Equal(t, `"a \"quoted\" value"`, QuoteString(`a "quoted" value`))
+	require.Equal(t, `"C:\\temp\\"`, QuoteString(`C:\temp\`))
+	require.Equal(t, `"x\" | delete"`, QuoteString(`x" | delete`))
+}
+
+func TestBindQuery(t *testing.T) {
+	query, err := BindQuery(`index=main user=$user$ bytes>$min$ | head $count$ | rex "(?<id>\d+)$"`, map[string]interface{}{
+		"user":  `bob" OR *`,
+		"min":   1.5,
+		"count": 10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, `index=main user="bob\" OR *" bytes>1.5 | head 10 | rex "(?<id>\d+)$"`, query)
+
+	query, err = BindQuery(`earliest=$start$ $field$ IN ($values$) $raw$`, map[string]interface{}{
+		"start":  time.Unix(1600000000, 0),
+		"field":  FieldName("data.user_id"),
+		"values": []string{"a", "b"},
+		"raw":    Raw("| head 1"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, `earliest=1600000000.000 data.user_id IN ("a","b") | head 1`, query)
+
+	_, err = BindQuery(`user=$user$`, nil)
+	require.Error(t, err)
+	_, err = BindQuery(`$field$=1`, map[string]interface{}{"field": FieldName("host | delete")})
+	require.Error(t, err)
+	_, err = BindQuery(`x=$x$`, map[string]interface{}{"x": struct{}{}})
+	require.Error(t, err)
+}
+
+func TestQueryBuilder(t *testing.T) {
+	query, err := NewQuery().
+		Index("main", "security").
+		Where("host", "web-1").
+		WhereNot("status", 200).
+		WhereIn("user", "alice", `bob"`).
+		Text("failed login").
+		Pipe("stats count by $field$", map[string]interface{}{"field": FieldName("user")}).
+		Pipe("| head $count$", map[string]interface{}{"count": 5}).
+		Build()
+	require.NoError(t, err)
+	require.Equal(t, `(index="main" OR index="security") host="web-1" NOT status=200 user IN ("alice","bob\"") "failed login" | stats count by user | head 5`, query)
+
+	query, err = NewQuery().Pipe("head 1", nil).Build()
+	require.NoError(t, err)
+	require.Equal(t, "* | head 1", query)
+
+	_, err = NewQuery().Where("bad field", "x").Index("main").Build()
+	require.Error(t, err)
+	_, err = NewQuery().Pipe("  ", nil).Build()
+	require.Error(t, err)
+}
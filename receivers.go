@@ -0,0 +1,128 @@
+package splunk
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+const (
+	receiversSimpleSuffix = "/services/receivers/simple"
+	receiversStreamSuffix = "/services/receivers/stream"
+)
+
+// ReceiverMetadata is the metadata of data sent to the receivers endpoints.
+//
+// Empty fields use the defaults of the input
+type ReceiverMetadata struct {
+	Index      string
+	Source     string
+	SourceType string
+	Host       string
+}
+
+// url Builds the receiver URL with the metadata as parameters
+func (m *ReceiverMetadata) url(baseURL, suffix string) string {
+	params := url.Values{}
+	if m != nil {
+		for key, value := range map[string]string{
+			"index":      m.Index,
+			"source":     m.Source,
+			"sourcetype": m.SourceType,
+			"host":       m.Host,
+		} {
+			if value != "" {
+				params.Add(key, value)
+			}
+		}
+	}
+	if len(params) == 0 {
+		return baseURL + suffix
+	}
+	return fmt.Sprintf("%s%s?%s", baseURL, suffix, params.Encode())
+}
+
+// SubmitEvents sends raw data to splunk through the management port, for when HEC is not enabled.
+// Splunk will break it into events using the sourcetype.
+func (c *Client) SubmitEvents(ctx context.Context, data []byte, metadata *ReceiverMetadata) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.url(c.config.BaseURL, receiversSimpleSuffix), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.MakeRequest(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("bad status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// ReceiverStream is a connection streaming data to splunk.  Close it to finish the request
+type ReceiverStream struct {
+	writer *io.PipeWriter
+	done   chan error
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// StreamEvents opens a connection to stream raw data to splunk through the management port.
+// Anything written to the stream is sent as it is written, and splunk will break it into events using the sourcetype.
+//
+// The stream must be closed to finish the request, which returns any error from splunk.
+func (c *Client) StreamEvents(ctx context.Context, metadata *ReceiverMetadata) (*ReceiverStream, error) {
+	reader, writer := io.Pipe()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.url(c.config.BaseURL, receiversStreamSuffix), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("X-Splunk-Input-Mode", "Streaming")
+
+	s := &ReceiverStream{
+		writer: writer,
+		done:   make(chan error, 1),
+	}
+	go func() {
+		resp, err := c.MakeRequest(req)
+		if err != nil {
+			reader.CloseWithError(err)
+			s.done <- err
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			body, _ := ioutil.ReadAll(resp.Body)
+			err := fmt.Errorf("bad status code: %d, body: %s", resp.StatusCode, string(body))
+			reader.CloseWithError(err)
+			s.done <- err
+			return
+		}
+		s.done <- nil
+	}()
+
+	return s, nil
+}
+
+// Write data to the stream
+func (s *ReceiverStream) Write(p []byte) (int, error) {
+	return s.writer.Write(p)
+}
+
+// Close the stream and wait for splunk to respond.  Closing it again returns the same error
+func (s *ReceiverStream) Close() error {
+	s.closeOnce.Do(func() {
+		s.writer.Close()
+		s.closeErr = <-s.done
+	})
+	return s.closeErr
+}
@@ -0,0 +1,113 @@
+package splunk
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_SubmitEvents(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method != http.MethodPost:
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			case req.RequestURI != "/services/receivers/simple?host=web01&index=main&sourcetype=syslog":
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			if string(b) != "line 1\nline 2\n" {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			rw.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		client := &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		}
+		err := client.SubmitEvents(context.Background(), []byte("line 1\nline 2\n"), &ReceiverMetadata{Index: "main", SourceType: "syslog", Host: "web01"})
+		require.NoError(t, err)
+	})
+}
+
+func TestClient_StreamEvents(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method != http.MethodPost:
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			case req.RequestURI != "/services/receivers/stream?index=main":
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			case req.Header.Get("X-Splunk-Input-Mode") != "Streaming":
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			if string(b) != "line 1\nline 2\n" {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			rw.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		client := &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		}
+		stream, err := client.StreamEvents(context.Background(), &ReceiverMetadata{Index: "main"})
+		require.NoError(t, err)
+		_, err = stream.Write([]byte("line 1\n"))
+		require.NoError(t, err)
+		_, err = stream.Write([]byte("line 2\n"))
+		require.NoError(t, err)
+		require.NoError(t, stream.Close())
+		require.NoError(t, stream.Close())
+	})
+
+	t.Run("bad status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+		client := &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		}
+		stream, err := client.StreamEvents(context.Background(), nil)
+		require.NoError(t, err)
+		stream.Write([]byte("line 1\n"))
+		err = stream.Close()
+		require.Error(t, err)
+		require.Equal(t, err, stream.Close())
+	})
+}
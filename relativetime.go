@@ -0,0 +1,250 @@
+package splunk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeUnit is a unit of a splunk relative time modifier
+type TimeUnit string
+
+// The units of a relative time modifier
+const (
+	TimeUnitSecond  TimeUnit = "s"
+	TimeUnitMinute  TimeUnit = "m"
+	TimeUnitHour    TimeUnit = "h"
+	TimeUnitDay     TimeUnit = "d"
+	TimeUnitWeek    TimeUnit = "w"
+	TimeUnitMonth   TimeUnit = "mon"
+	TimeUnitQuarter TimeUnit = "q"
+	TimeUnitYear    TimeUnit = "y"
+)
+
+// timeUnitAliases are the names splunk accepts for each unit
+var timeUnitAliases = map[string]TimeUnit{
+	"s": TimeUnitSecond, "sec": TimeUnitSecond, "secs": TimeUnitSecond, "second": TimeUnitSecond, "seconds": TimeUnitSecond,
+	"m": TimeUnitMinute, "min": TimeUnitMinute, "mins": TimeUnitMinute, "minute": TimeUnitMinute, "minutes": TimeUnitMinute,
+	"h": TimeUnitHour, "hr": TimeUnitHour, "hrs": TimeUnitHour, "hour": TimeUnitHour, "hours": TimeUnitHour,
+	"d": TimeUnitDay, "day": TimeUnitDay, "days": TimeUnitDay,
+	"w": TimeUnitWeek, "week": TimeUnitWeek, "weeks": TimeUnitWeek,
+	"mon": TimeUnitMonth, "month": TimeUnitMonth, "months": TimeUnitMonth,
+	"q": TimeUnitQuarter, "qtr": TimeUnitQuarter, "qtrs": TimeUnitQuarter, "quarter": TimeUnitQuarter, "quarters": TimeUnitQuarter,
+	"y": TimeUnitYear, "yr": TimeUnitYear, "yrs": TimeUnitYear, "year": TimeUnitYear, "years": TimeUnitYear,
+}
+
+// RelativeTimeStep is a single offset or snap of a relative time modifier
+type RelativeTimeStep struct {
+	// Snap to the start of the unit instead of offsetting by Amount
+	Snap   bool
+	Amount int
+	Unit   TimeUnit
+	// For snaps to a week day (@w0 to @w7), the day to snap to.  Only used when Unit is TimeUnitWeek
+	Weekday time.Weekday
+}
+
+// RelativeTime is a splunk relative time modifier, such as "-24h@h", "@d", "now" or "rt-5m".
+//
+// The steps are applied in order to the current time.  No steps means now.
+type RelativeTime struct {
+	// Real-time search window, the "rt" prefix
+	RealTime bool
+	Steps    []RelativeTimeStep
+}
+
+// RelativeNow Returns the relative time for now
+func RelativeNow() RelativeTime {
+	return RelativeTime{}
+}
+
+// Add Returns the relative time offset by amount units, ex: Add(-24, TimeUnitHour) for "-24h"
+func (r RelativeTime) Add(amount int, unit TimeUnit) RelativeTime {
+	return r.with(RelativeTimeStep{Amount: amount, Unit: unit})
+}
+
+// Snap Returns the relative time snapped to the start of the unit, ex: Snap(TimeUnitDay) for "@d"
+func (r RelativeTime) Snap(unit TimeUnit) RelativeTime {
+	return r.with(RelativeTimeStep{Snap: true, Unit: unit})
+}
+
+// SnapWeekday Returns the relative time snapped to the start of the most recent week day, ex: SnapWeekday(time.Monday) for "@w1"
+func (r RelativeTime) SnapWeekday(weekday time.Weekday) RelativeTime {
+	return r.with(RelativeTimeStep{Snap: true, Unit: TimeUnitWeek, Weekday: weekday})
+}
+
+// with Returns a copy of the relative time with the step added
+func (r RelativeTime) with(step RelativeTimeStep) RelativeTime {
+	steps := make([]RelativeTimeStep, len(r.Steps), len(r.Steps)+1)
+	copy(steps, r.Steps)
+	r.Steps = append(steps, step)
+	return r
+}
+
+// String Returns the modifier to send to splunk, ex: "-24h@h"
+func (r RelativeTime) String() string {
+	builder := strings.Builder{}
+	if r.RealTime {
+		builder.WriteString("rt")
+	}
+	for _, step := range r.Steps {
+		if step.Snap {
+			builder.WriteString("@")
+			builder.WriteString(string(step.Unit))
+			if step.Unit == TimeUnitWeek && step.Weekday != time.Sunday {
+				builder.WriteString(strconv.Itoa(int(step.Weekday)))
+			}
+			continue
+		}
+		if step.Amount >= 0 {
+			builder.WriteString("+")
+		}
+		builder.WriteString(strconv.Itoa(step.Amount))
+		builder.WriteString(string(step.Unit))
+	}
+	if len(r.Steps) == 0 {
+		if r.RealTime {
+			return "rtnow"
+		}
+		return "now"
+	}
+	return builder.String()
+}
+
+// ParseRelativeTime Parses a splunk relative time modifier, such as "-24h@h", "@w1", "now" or "rt-5m"
+func ParseRelativeTime(modifier string) (RelativeTime, error) {
+	r := RelativeTime{}
+	s := strings.ToLower(strings.TrimSpace(modifier))
+	if strings.HasPrefix(s, "rt") {
+		r.RealTime = true
+		s = strings.TrimPrefix(s, "rt")
+	}
+	if s == "now" || s == "" {
+		return r, nil
+	}
+
+	for len(s) > 0 {
+		step := RelativeTimeStep{}
+		switch s[0] {
+		case '@':
+			step.Snap = true
+			s = s[1:]
+		case '+', '-':
+			sign := 1
+			if s[0] == '-' {
+				sign = -1
+			}
+			s = s[1:]
+			digits := leadingLength(s, isDigit)
+			step.Amount = 1
+			if digits > 0 {
+				amount, err := strconv.Atoi(s[:digits])
+				if err != nil {
+					return RelativeTime{}, fmt.Errorf("bad amount in relative time %q: %s", modifier, err)
+				}
+				step.Amount = amount
+			}
+			step.Amount *= sign
+			s = s[digits:]
+		default:
+			return RelativeTime{}, fmt.Errorf("bad relative time %q: unexpected %q", modifier, s[0])
+		}
+
+		letters := leadingLength(s, isLetter)
+		unit, ok := timeUnitAliases[s[:letters]]
+		if !ok {
+			return RelativeTime{}, fmt.Errorf("bad unit in relative time %q: %q", modifier, s[:letters])
+		}
+		step.Unit = unit
+		s = s[letters:]
+
+		// Week day snaps, @w0 to @w6, and @w7 which is Sunday like @w0
+		if step.Snap && unit == TimeUnitWeek && len(s) > 0 && isDigit(s[0]) {
+			if s[0] > '7' {
+				return RelativeTime{}, fmt.Errorf("bad week day in relative time %q: %c", modifier, s[0])
+			}
+			step.Weekday = time.Weekday((s[0] - '0') % 7)
+			s = s[1:]
+		}
+
+		r.Steps = append(r.Steps, step)
+	}
+
+	return r, nil
+}
+
+// leadingLength Returns how many leading bytes of s match
+func leadingLength(s string, match func(byte) bool) int {
+	i := 0
+	for i < len(s) && match(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isLetter(b byte) bool {
+	return b >= 'a' && b <= 'z'
+}
+
+// Time Evaluates the relative time against the reference time, in the location of the reference time.
+// Snaps use the calendar of that location, so use reference.In to evaluate in another time zone.
+func (r RelativeTime) Time(reference time.Time) time.Time {
+	t := reference
+	for _, step := range r.Steps {
+		if step.Snap {
+			t = snapTime(t, step)
+			continue
+		}
+		switch step.Unit {
+		case TimeUnitSecond:
+			t = t.Add(time.Duration(step.Amount) * time.Second)
+		case TimeUnitMinute:
+			t = t.Add(time.Duration(step.Amount) * time.Minute)
+		case TimeUnitHour:
+			t = t.Add(time.Duration(step.Amount) * time.Hour)
+		case TimeUnitDay:
+			t = t.AddDate(0, 0, step.Amount)
+		case TimeUnitWeek:
+			t = t.AddDate(0, 0, step.Amount*7)
+		case TimeUnitMonth:
+			t = t.AddDate(0, step.Amount, 0)
+		case TimeUnitQuarter:
+			t = t.AddDate(0, step.Amount*3, 0)
+		case TimeUnitYear:
+			t = t.AddDate(step.Amount, 0, 0)
+		}
+	}
+	return t
+}
+
+// snapTime Snaps the time back to the start of the step's unit
+func snapTime(t time.Time, step RelativeTimeStep) time.Time {
+	location := t.Location()
+	year, month, day := t.Date()
+	switch step.Unit {
+	case TimeUnitSecond:
+		return time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), 0, location)
+	case TimeUnitMinute:
+		return time.Date(year, month, day, t.Hour(), t.Minute(), 0, 0, location)
+	case TimeUnitHour:
+		return time.Date(year, month, day, t.Hour(), 0, 0, 0, location)
+	case TimeUnitDay:
+		return time.Date(year, month, day, 0, 0, 0, 0, location)
+	case TimeUnitWeek:
+		// Back to the most recent week day, today if it is that day
+		daysBack := (int(t.Weekday()) - int(step.Weekday) + 7) % 7
+		return time.Date(year, month, day-daysBack, 0, 0, 0, 0, location)
+	case TimeUnitMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, location)
+	case TimeUnitQuarter:
+		quarterMonth := time.Month((int(month)-1)/3*3 + 1)
+		return time.Date(year, quarterMonth, 1, 0, 0, 0, 0, location)
+	case TimeUnitYear:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, location)
+	}
+	return t
+}
@@ -0,0 +1,77 @@
+package splunk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRelativeTime(t *testing.T) {
+	for modifier, expected := range map[string]string{
+		"now":          "now",
+		"":             "now",
+		"-24h@h":       "-24h@h",
+		"@d":           "@d",
+		"-d":           "-1d",
+		"-7days@w1":    "-7d@w1",
+		"@w0":          "@w",
+		"@w7":          "@w",
+		"-1mon@mon+3d": "-1mon@mon+3d",
+		"rt-5m":        "rt-5m",
+		"rtnow":        "rtnow",
+		"-2Qtr@q":      "-2q@q",
+		"+30sec":       "+30s",
+	} {
+		r, err := ParseRelativeTime(modifier)
+		require.NoError(t, err, modifier)
+		require.Equal(t, expected, r.String(), modifier)
+	}
+
+	for _, modifier := range []string{"24h", "-24x", "@w8", "-1h@", "yesterday"} {
+		_, err := ParseRelativeTime(modifier)
+		require.Error(t, err, modifier)
+	}
+}
+
+func TestRelativeTime_Builder(t *testing.T) {
+	r := RelativeNow().Add(-24, TimeUnitHour).Snap(TimeUnitHour)
+	require.Equal(t, "-24h@h", r.String())
+	require.Equal(t, "-7d@w1", RelativeNow().Add(-7, TimeUnitDay).SnapWeekday(time.Monday).String())
+	require.Equal(t, RelativeTime{RealTime: true, Steps: []RelativeTimeStep{{Amount: -5, Unit: TimeUnitMinute}}}, RelativeTime{RealTime: true}.Add(-5, TimeUnitMinute))
+}
+
+func TestRelativeTime_Time(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	// Wednesday
+	reference := time.Date(2020, 9, 16, 14, 35, 20, 0, time.UTC)
+
+	for modifier, expected := range map[string]time.Time{
+		"now":     reference,
+		"-24h@h":  time.Date(2020, 9, 15, 14, 0, 0, 0, time.UTC),
+		"@d":      time.Date(2020, 9, 16, 0, 0, 0, 0, time.UTC),
+		"-1d@d":   time.Date(2020, 9, 15, 0, 0, 0, 0, time.UTC),
+		"@w0":     time.Date(2020, 9, 13, 0, 0, 0, 0, time.UTC),
+		"@w7":     time.Date(2020, 9, 13, 0, 0, 0, 0, time.UTC),
+		"@w3":     time.Date(2020, 9, 16, 0, 0, 0, 0, time.UTC),
+		"@w5":     time.Date(2020, 9, 11, 0, 0, 0, 0, time.UTC),
+		"@mon":    time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC),
+		"@q":      time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC),
+		"-1y@y":   time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		"@d+3h":   time.Date(2020, 9, 16, 3, 0, 0, 0, time.UTC),
+		"rt-5m@m": time.Date(2020, 9, 16, 14, 30, 0, 0, time.UTC),
+		"-90s@s":  time.Date(2020, 9, 16, 14, 33, 50, 0, time.UTC),
+	} {
+		r, err := ParseRelativeTime(modifier)
+		require.NoError(t, err, modifier)
+		require.True(t, expected.Equal(r.Time(reference)), "%s evaluated to %s", modifier, r.Time(reference))
+	}
+
+	// Snaps use the calendar of the reference time zone
+	r, err := ParseRelativeTime("@d")
+	require.NoError(t, err)
+	require.True(t, time.Date(2020, 9, 16, 0, 0, 0, 0, newYork).Equal(r.Time(reference.In(newYork))))
+}
@@ -0,0 +1,167 @@
+package splunk
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// ResultDecodeOptions are options for decoding the results of a search into structs
+type ResultDecodeOptions struct {
+	// Decoder used for each row.  If nil, rows are decoded like UnMarshal
+	Decoder *Decoder
+	// Skip rows that fail to decode instead of stopping at the first one
+	SkipErrors bool
+	// Called with each skipped row when SkipErrors is set in DecodeAllResults
+	ErrorHandler func(err *RowError)
+}
+
+// DecodedResult is a single row of search results decoded into a struct
+type DecodedResult struct {
+	// Index of the row in the results, starting at 0
+	Row int
+	// Pointer to the decoded struct, filled in as much as possible even if there is an error
+	Value interface{}
+	// The row before decoding
+	Result SearchResult
+	// Error decoding the row, nil if it decoded successfully
+	Err error
+}
+
+// RowError is an error decoding a single row of search results
+type RowError struct {
+	Row    int
+	Result SearchResult
+	Err    error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("failed to decode row %d: %s", e.Row, e.Err)
+}
+
+// Unwrap Returns the underlying error
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// structTypeOf Returns the struct type of a struct, or pointer to a struct
+func structTypeOf(v interface{}) (reflect.Type, error) {
+	typeOf := reflect.TypeOf(v)
+	if typeOf != nil && typeOf.Kind() == reflect.Ptr {
+		typeOf = typeOf.Elem()
+	}
+	if typeOf == nil || typeOf.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("must decode into a struct, got %T", v)
+	}
+	return typeOf, nil
+}
+
+// DecodeResults Gets a channel of results from the search job decoded into structs, see GetResults.
+//
+// Template is a struct, or pointer to a struct, of the type to decode into.  The Value of each DecodedResult
+// is a pointer to a new struct of that type.
+//
+// Rows that fail to decode are sent with their Err set.  Unless SkipErrors is set, the channel is closed after the first one.
+func (s *Search) DecodeResults(ctx context.Context, template interface{}, options *ResultDecodeOptions) (chan DecodedResult, error) {
+	structType, err := structTypeOf(template)
+	if err != nil {
+		return nil, err
+	}
+	if options == nil {
+		options = &ResultDecodeOptions{}
+	}
+	decoder := options.Decoder
+	if decoder == nil {
+		decoder = &Decoder{}
+	}
+
+	// Stop getting results if we stop early
+	resultsContext, cancel := context.WithCancel(ctx)
+	results, err := s.GetResults(resultsContext)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	decoded := make(chan DecodedResult, cap(results))
+	go func() {
+		defer close(decoded)
+		defer cancel()
+
+		row := 0
+		for result := range results {
+			value := reflect.New(structType)
+			decodedResult := DecodedResult{
+				Row:    row,
+				Value:  value.Interface(),
+				Result: result,
+			}
+			if err := decoder.Decode(result, decodedResult.Value); err != nil {
+				decodedResult.Err = &RowError{Row: row, Result: result, Err: err}
+			}
+			row++
+
+			select {
+			case decoded <- decodedResult:
+			case <-ctx.Done():
+				return
+			}
+			if decodedResult.Err != nil && !options.SkipErrors {
+				return
+			}
+		}
+	}()
+
+	return decoded, nil
+}
+
+// DecodeAllResults Decodes every result of the search job into the slice v points to, see DecodeResults.
+//
+// V must be a pointer to a slice of structs or struct pointers, such as *[]MyStruct or *[]*MyStruct.  Decoded rows are appended to it.
+//
+// Unless SkipErrors is set, it stops and returns a *RowError at the first row that fails to decode.
+// If SkipErrors is set, those rows are left out and passed to the ErrorHandler.
+func (s *Search) DecodeAllResults(ctx context.Context, v interface{}, options *ResultDecodeOptions) error {
+	sliceValue := reflect.ValueOf(v)
+	if sliceValue.Kind() != reflect.Ptr || sliceValue.IsNil() || sliceValue.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("must decode into a pointer to a slice, got %T", v)
+	}
+	sliceValue = sliceValue.Elem()
+	elemType := sliceValue.Type().Elem()
+	isPointer := elemType.Kind() == reflect.Ptr
+	structType := elemType
+	if isPointer {
+		structType = elemType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("must decode into a slice of structs, got %T", v)
+	}
+	if options == nil {
+		options = &ResultDecodeOptions{}
+	}
+
+	decoded, err := s.DecodeResults(ctx, reflect.New(structType).Interface(), options)
+	if err != nil {
+		return err
+	}
+	for result := range decoded {
+		if result.Err != nil {
+			rowErr := result.Err.(*RowError)
+			if !options.SkipErrors {
+				return rowErr
+			}
+			if options.ErrorHandler != nil {
+				options.ErrorHandler(rowErr)
+			}
+			continue
+		}
+
+		value := reflect.ValueOf(result.Value)
+		if !isPointer {
+			value = value.Elem()
+		}
+		sliceValue.Set(reflect.Append(sliceValue, value))
+	}
+
+	return ctx.Err()
+}
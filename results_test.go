@@ -0,0 +1,124 @@
+package splunk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newResultsTestSearch returns a search whose results are a single page with the body
+func newResultsTestSearch(body string) (*Search, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method != http.MethodGet:
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		case req.URL.Path != "/services/search/jobs/job_id_1/results_preview":
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.URL.Query().Get("offset") != "0" {
+			rw.Write([]byte(`{"preview":false,"results":[]}`))
+			return
+		}
+		rw.Write([]byte(body))
+	}))
+	search := &Search{
+		SearchID: "job_id_1",
+		client: &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		},
+	}
+	return search, server.Close
+}
+
+type resultsTestStruct struct {
+	Host  string `splunk:"host"`
+	Count int    `splunk:"count"`
+}
+
+func TestSearch_DecodeResults(t *testing.T) {
+	search, closeServer := newResultsTestSearch(`{"preview":false,"results":[{"host":"web01","count":"1"},{"host":"web02","count":"bad"},{"host":"web03","count":"3"}]}`)
+	defer closeServer()
+
+	t.Run("stop on error", func(t *testing.T) {
+		decoded, err := search.DecodeResults(context.Background(), resultsTestStruct{}, nil)
+		require.NoError(t, err)
+		results := []DecodedResult{}
+		for result := range decoded {
+			results = append(results, result)
+		}
+		require.Len(t, results, 2)
+		require.NoError(t, results[0].Err)
+		require.Equal(t, &resultsTestStruct{Host: "web01", Count: 1}, results[0].Value)
+		require.Error(t, results[1].Err)
+		require.Equal(t, 1, results[1].Row)
+		require.Equal(t, "web02", results[1].Value.(*resultsTestStruct).Host)
+	})
+
+	t.Run("skip errors", func(t *testing.T) {
+		decoded, err := search.DecodeResults(context.Background(), &resultsTestStruct{}, &ResultDecodeOptions{SkipErrors: true})
+		require.NoError(t, err)
+		results := []DecodedResult{}
+		for result := range decoded {
+			results = append(results, result)
+		}
+		require.Len(t, results, 3)
+		require.Error(t, results[1].Err)
+		require.Equal(t, &resultsTestStruct{Host: "web03", Count: 3}, results[2].Value)
+	})
+
+	t.Run("not a struct", func(t *testing.T) {
+		_, err := search.DecodeResults(context.Background(), "string", nil)
+		require.Error(t, err)
+	})
+}
+
+func TestSearch_DecodeAllResults(t *testing.T) {
+	search, closeServer := newResultsTestSearch(`{"preview":false,"results":[{"host":"web01","count":"1"},{"host":"web02","count":"bad"},{"host":"web03","count":"3"}]}`)
+	defer closeServer()
+
+	t.Run("stop on error", func(t *testing.T) {
+		results := []resultsTestStruct{}
+		err := search.DecodeAllResults(context.Background(), &results, nil)
+		rowErr := &RowError{}
+		require.True(t, errors.As(err, &rowErr))
+		require.Equal(t, 1, rowErr.Row)
+		require.Equal(t, []resultsTestStruct{{Host: "web01", Count: 1}}, results)
+	})
+
+	t.Run("skip errors", func(t *testing.T) {
+		results := []*resultsTestStruct{}
+		skipped := []*RowError{}
+		err := search.DecodeAllResults(context.Background(), &results, &ResultDecodeOptions{
+			SkipErrors:   true,
+			ErrorHandler: func(err *RowError) { skipped = append(skipped, err) },
+		})
+		require.NoError(t, err)
+		require.Equal(t, []*resultsTestStruct{{Host: "web01", Count: 1}, {Host: "web03", Count: 3}}, results)
+		require.Len(t, skipped, 1)
+		require.Equal(t, "web02", skipped[0].Result["host"])
+	})
+
+	t.Run("strict decoder", func(t *testing.T) {
+		results := []resultsTestStruct{}
+		err := search.DecodeAllResults(context.Background(), &results, &ResultDecodeOptions{
+			Decoder:    &Decoder{DisallowUnknownFields: true},
+			SkipErrors: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+	})
+
+	t.Run("not a slice", func(t *testing.T) {
+		results := resultsTestStruct{}
+		require.Error(t, search.DecodeAllResults(context.Background(), &results, nil))
+	})
+}
@@ -0,0 +1,350 @@
+package splunk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	savedSearchesSuffix       = "/services/saved/searches"
+	savedSearchSuffix         = "/services/saved/searches/%s"
+	savedSearchACLSuffix      = "/servicesNS/%s/%s/saved/searches/%s/acl"
+	savedSearchDispatchSuffix = "/services/saved/searches/%s/dispatch"
+	savedSearchHistorySuffix  = "/services/saved/searches/%s/history"
+)
+
+// SavedSearch is a saved search (report or alert) stored in splunk
+//
+// When creating or updating, only the fields that are set are sent: empty strings, zero numbers and nil booleans are left out,
+// so an update changes only those fields.  Use Bool to set a boolean.
+// Any other parameter from [the documentation](https://docs.splunk.com/Documentation/Splunk/8.0.5/RESTREF/RESTsearch#saved.2Fsearches)
+// can be set with Params.
+type SavedSearch struct {
+	Name        string `json:"-"`
+	Search      string `json:"search"`
+	Description string `json:"description"`
+	Disabled    *bool  `json:"disabled"`
+
+	// Schedule
+	IsScheduled  *bool  `json:"is_scheduled"`
+	CronSchedule string `json:"cron_schedule"`
+
+	// Dispatch time range, ex: "-24h@h" and "now"
+	DispatchEarliestTime string `json:"dispatch.earliest_time"`
+	DispatchLatestTime   string `json:"dispatch.latest_time"`
+
+	// Alert conditions
+	AlertType       string `json:"alert_type"`
+	AlertComparator string `json:"alert_comparator"`
+	AlertThreshold  string `json:"alert_threshold"`
+	AlertCondition  string `json:"alert_condition"`
+	AlertSeverity   int    `json:"alert.severity"`
+	AlertTrack      *bool  `json:"alert.track"`
+
+	// Alert actions
+	//
+	// Actions is a comma separated list of enabled actions, ex: "email,webhook".
+	// ActionParams are the action.* settings without the "action." prefix, ex: "email.to"
+	Actions      string            `json:"actions"`
+	ActionParams map[string]string `json:"-"`
+
+	// ACL is filled in when fetching, it is not sent when creating or updating.
+	// Use UpdateSavedSearchACL to change it.
+	ACL ACL `json:"-"`
+
+	// Params are any other parameters to send when creating or updating
+	Params map[string]string `json:"-"`
+}
+
+// Bool Returns a pointer to the boolean, to set optional boolean fields such as SavedSearch.IsScheduled
+func Bool(value bool) *bool {
+	return &value
+}
+
+// ACL is the access control list of a splunk object
+type ACL struct {
+	App        string `json:"app"`
+	Owner      string `json:"owner"`
+	Sharing    string `json:"sharing"`
+	Perms      Perms  `json:"perms"`
+	CanWrite   bool   `json:"can_write"`
+	Modifiable bool   `json:"modifiable"`
+}
+
+// Perms are the roles that can read and write an object
+type Perms struct {
+	Read  []string `json:"read"`
+	Write []string `json:"write"`
+}
+
+// savedSearchesResponse is what splunk returns when fetching saved searches
+type savedSearchesResponse struct {
+	Entry []struct {
+		Name    string          `json:"name"`
+		ACL     ACL             `json:"acl"`
+		Content json.RawMessage `json:"content"`
+	} `json:"entry"`
+	Paging Paging `json:"paging"`
+}
+
+// params Converts the saved search to the parameters to send to splunk
+func (s *SavedSearch) params() map[string]string {
+	params := map[string]string{}
+	boolParams := map[string]*bool{
+		"disabled":     s.Disabled,
+		"is_scheduled": s.IsScheduled,
+		"alert.track":  s.AlertTrack,
+	}
+	for key, value := range boolParams {
+		if value != nil {
+			params[key] = strconv.FormatBool(*value)
+		}
+	}
+	stringParams := map[string]string{
+		"search":                 s.Search,
+		"description":            s.Description,
+		"cron_schedule":          s.CronSchedule,
+		"dispatch.earliest_time": s.DispatchEarliestTime,
+		"dispatch.latest_time":   s.DispatchLatestTime,
+		"alert_type":             s.AlertType,
+		"alert_comparator":       s.AlertComparator,
+		"alert_threshold":        s.AlertThreshold,
+		"alert_condition":        s.AlertCondition,
+		"actions":                s.Actions,
+	}
+	for key, value := range stringParams {
+		if value != "" {
+			params[key] = value
+		}
+	}
+	if s.AlertSeverity != 0 {
+		params["alert.severity"] = strconv.Itoa(s.AlertSeverity)
+	}
+	for key, value := range s.ActionParams {
+		params["action."+key] = value
+	}
+	for key, value := range s.Params {
+		params[key] = value
+	}
+	return params
+}
+
+// CreateSavedSearch Creates a new saved search
+func (c *Client) CreateSavedSearch(ctx context.Context, savedSearch *SavedSearch) error {
+	params := savedSearch.params()
+	params["name"] = savedSearch.Name
+
+	resp, err := c.BuildResponse(ctx, http.MethodPost, savedSearchesSuffix, params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 201 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("bad status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// GetSavedSearch Gets a single saved search by name
+func (c *Client) GetSavedSearch(ctx context.Context, name string) (*SavedSearch, error) {
+	savedSearches, err := c.getSavedSearches(ctx, fmt.Sprintf(savedSearchSuffix, url.PathEscape(name)), nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(savedSearches) == 0 {
+		return nil, fmt.Errorf("no saved search found")
+	}
+	return savedSearches[0], nil
+}
+
+// ListSavedSearches Lists all saved searches visible to the user
+//
+// Params are any other parameters you want to specific, such as "search" to filter the results
+func (c *Client) ListSavedSearches(ctx context.Context, params map[string]string) ([]*SavedSearch, error) {
+	paramsToSend := map[string]string{"count": "0"}
+	for key, value := range params {
+		paramsToSend[key] = value
+	}
+	return c.getSavedSearches(ctx, savedSearchesSuffix, paramsToSend)
+}
+
+// getSavedSearches fetches and parses the saved searches at the suffix
+func (c *Client) getSavedSearches(ctx context.Context, suffix string, params map[string]string) ([]*SavedSearch, error) {
+	resp, err := c.BuildResponse(ctx, http.MethodGet, suffix, params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("bad status code: %d", resp.StatusCode)
+	}
+
+	result := savedSearchesResponse{}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %s", err)
+	}
+
+	savedSearches := make([]*SavedSearch, 0, len(result.Entry))
+	for _, entry := range result.Entry {
+		savedSearch := &SavedSearch{
+			Name:         entry.Name,
+			ACL:          entry.ACL,
+			ActionParams: map[string]string{},
+		}
+		if err := json.Unmarshal(entry.Content, savedSearch); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal saved search %s: %s", entry.Name, err)
+		}
+
+		// Pull out the action.* settings
+		content := map[string]interface{}{}
+		if err := json.Unmarshal(entry.Content, &content); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal saved search %s: %s", entry.Name, err)
+		}
+		for key, value := range content {
+			if strings.HasPrefix(key, "action.") && value != nil {
+				savedSearch.ActionParams[strings.TrimPrefix(key, "action.")] = fmt.Sprintf("%v", value)
+			}
+		}
+
+		savedSearches = append(savedSearches, savedSearch)
+	}
+
+	return savedSearches, nil
+}
+
+// UpdateSavedSearch Updates an existing saved search with the fields set in savedSearch.  Fields that are not set are left as they are
+func (c *Client) UpdateSavedSearch(ctx context.Context, savedSearch *SavedSearch) error {
+	resp, err := c.BuildResponse(ctx, http.MethodPost, fmt.Sprintf(savedSearchSuffix, url.PathEscape(savedSearch.Name)), savedSearch.params())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("bad status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// DeleteSavedSearch Deletes a saved search by name
+func (c *Client) DeleteSavedSearch(ctx context.Context, name string) error {
+	resp, err := c.BuildResponse(ctx, http.MethodDelete, fmt.Sprintf(savedSearchSuffix, url.PathEscape(name)), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("bad status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// UpdateSavedSearchACL Updates the sharing and permissions of a saved search.
+//
+// The App and Owner of the acl are used to find the saved search, and the owner is set to acl.Owner
+func (c *Client) UpdateSavedSearchACL(ctx context.Context, name string, acl *ACL) error {
+	params := map[string]string{
+		"sharing":     acl.Sharing,
+		"owner":       acl.Owner,
+		"perms.read":  strings.Join(acl.Perms.Read, ","),
+		"perms.write": strings.Join(acl.Perms.Write, ","),
+	}
+	suffix := fmt.Sprintf(savedSearchACLSuffix, url.PathEscape(acl.Owner), url.PathEscape(acl.App), url.PathEscape(name))
+	resp, err := c.BuildResponse(ctx, http.MethodPost, suffix, params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("bad status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// DispatchSavedSearch Dispatches a saved search and returns the search job
+//
+// Args are the args.* overrides to substitute in the saved search, without the "args." prefix.
+// Params are any other parameters, such as "dispatch.earliest_time" or "trigger_actions", from
+// [the documentation](https://docs.splunk.com/Documentation/Splunk/8.0.5/RESTREF/RESTsearch#saved.2Fsearches.2F.7Bname.7D.2Fdispatch)
+func (c *Client) DispatchSavedSearch(ctx context.Context, name string, args map[string]string, params map[string]string) (*Search, error) {
+	paramsToSend := map[string]string{}
+	for key, value := range params {
+		paramsToSend[key] = value
+	}
+	for key, value := range args {
+		paramsToSend["args."+key] = value
+	}
+
+	resp, err := c.BuildResponse(ctx, http.MethodPost, fmt.Sprintf(savedSearchDispatchSuffix, url.PathEscape(name)), paramsToSend)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 201 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("bad status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	search := &Search{}
+	err = json.NewDecoder(resp.Body).Decode(search)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %s", err)
+	}
+	search.client = c
+
+	return search, nil
+}
+
+// SavedSearchRun is a past run of a saved search
+type SavedSearchRun struct {
+	*Search
+	Published     time.Time
+	SearchContent SearchContent
+}
+
+// GetSavedSearchHistory Gets the past runs of a saved search that still exist on the server, newest first.
+//
+// Use SearchContent.IsScheduled to find runs started by the scheduler, and the Search to fetch their results.
+func (c *Client) GetSavedSearchHistory(ctx context.Context, name string) ([]*SavedSearchRun, error) {
+	resp, err := c.BuildResponse(ctx, http.MethodGet, fmt.Sprintf(savedSearchHistorySuffix, url.PathEscape(name)), map[string]string{"count": "0"})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("bad status code: %d", resp.StatusCode)
+	}
+
+	result := JobSearchResult{}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %s", err)
+	}
+
+	runs := make([]*SavedSearchRun, 0, len(result.Entry))
+	for _, entry := range result.Entry {
+		// The published time is not always set, keep the zero time if so
+		published, _ := time.Parse(time.RFC3339, entry.Published)
+		runs = append(runs, &SavedSearchRun{
+			Search:        &Search{SearchID: entry.Name, client: c},
+			Published:     published,
+			SearchContent: entry.SearchContent,
+		})
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Published.After(runs[j].Published)
+	})
+
+	return runs, nil
+}
@@ -0,0 +1,306 @@
+package splunk
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_CreateSavedSearch(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method != http.MethodPost:
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			case req.RequestURI != "/services/saved/searches":
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			values, err := url.ParseQuery(string(b))
+			if err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if values.Get("name") != "errors" ||
+				values.Get("search") != "index=main error" ||
+				values.Get("cron_schedule") != "*/5 * * * *" ||
+				values.Get("is_scheduled") != "true" ||
+				values.Get("dispatch.earliest_time") != "-5m" ||
+				values.Get("alert.severity") != "4" ||
+				values.Get("action.email.to") != "team@example.com" ||
+				values.Get("description") != "" {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			rw.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+		client := &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		}
+		err := client.CreateSavedSearch(context.Background(), &SavedSearch{
+			Name:                 "errors",
+			Search:               "index=main error",
+			IsScheduled:          Bool(true),
+			CronSchedule:         "*/5 * * * *",
+			DispatchEarliestTime: "-5m",
+			AlertSeverity:        4,
+			Actions:              "email",
+			ActionParams:         map[string]string{"email.to": "team@example.com"},
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestClient_GetSavedSearch(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method != http.MethodGet:
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			case req.RequestURI != "/services/saved/searches/my%20errors?output_mode=json":
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			rw.Write([]byte(`{"entry":[{"name":"my errors","acl":{"app":"search","owner":"admin","sharing":"app","perms":{"read":["*"],"write":["admin"]}},"content":{"search":"index=main error","is_scheduled":true,"cron_schedule":"0 * * * *","dispatch.earliest_time":"-1h","alert.severity":3,"actions":"email","action.email":true,"action.email.to":"team@example.com"}}]}`))
+		}))
+		defer server.Close()
+		client := &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		}
+		savedSearch, err := client.GetSavedSearch(context.Background(), "my errors")
+		require.NoError(t, err)
+		require.Equal(t, "my errors", savedSearch.Name)
+		require.Equal(t, "index=main error", savedSearch.Search)
+		require.True(t, *savedSearch.IsScheduled)
+		require.Equal(t, "0 * * * *", savedSearch.CronSchedule)
+		require.Equal(t, "-1h", savedSearch.DispatchEarliestTime)
+		require.Equal(t, 3, savedSearch.AlertSeverity)
+		require.Equal(t, "true", savedSearch.ActionParams["email"])
+		require.Equal(t, "team@example.com", savedSearch.ActionParams["email.to"])
+		require.Equal(t, "app", savedSearch.ACL.Sharing)
+		require.Equal(t, []string{"admin"}, savedSearch.ACL.Perms.Write)
+	})
+}
+
+func TestClient_DeleteSavedSearch(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method != http.MethodDelete:
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			case req.RequestURI != "/services/saved/searches/errors?output_mode=json":
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			rw.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		client := &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		}
+		err := client.DeleteSavedSearch(context.Background(), "errors")
+		require.NoError(t, err)
+	})
+}
+
+func TestClient_UpdateSavedSearch(t *testing.T) {
+	t.Run("only set fields", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method != http.MethodPost:
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			case req.RequestURI != "/services/saved/searches/errors":
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if string(b) != "output_mode=json&search=index%3Dmain+error" {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			rw.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		client := &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		}
+		err := client.UpdateSavedSearch(context.Background(), &SavedSearch{Name: "errors", Search: "index=main error"})
+		require.NoError(t, err)
+	})
+
+	t.Run("booleans", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			b, _ := ioutil.ReadAll(req.Body)
+			if string(b) != "alert.track=true&disabled=false&output_mode=json" {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			rw.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		client := &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		}
+		err := client.UpdateSavedSearch(context.Background(), &SavedSearch{Name: "errors", Disabled: Bool(false), AlertTrack: Bool(true)})
+		require.NoError(t, err)
+	})
+}
+
+func TestClient_UpdateSavedSearchACL(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method != http.MethodPost:
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			case req.RequestURI != "/servicesNS/admin/search/saved/searches/errors/acl":
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			if string(b) != "output_mode=json&owner=admin&perms.read=%2A&perms.write=admin%2Cpower&sharing=app" {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			rw.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		client := &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		}
+		err := client.UpdateSavedSearchACL(context.Background(), "errors", &ACL{
+			App:     "search",
+			Owner:   "admin",
+			Sharing: "app",
+			Perms: Perms{
+				Read:  []string{"*"},
+				Write: []string{"admin", "power"},
+			},
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestClient_DispatchSavedSearch(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method != http.MethodPost:
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			case req.RequestURI != "/services/saved/searches/errors/dispatch":
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			if string(b) != "args.host=web01&dispatch.earliest_time=-1h&output_mode=json" {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			rw.WriteHeader(http.StatusCreated)
+			rw.Write([]byte(`{"sid":"admin__admin__search__errors_at_1600000000_1"}`))
+		}))
+		defer server.Close()
+		client := &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		}
+		search, err := client.DispatchSavedSearch(context.Background(), "errors",
+			map[string]string{"host": "web01"},
+			map[string]string{"dispatch.earliest_time": "-1h"},
+		)
+		require.NoError(t, err)
+		require.Equal(t, "admin__admin__search__errors_at_1600000000_1", search.SearchID)
+	})
+}
+
+func TestClient_GetSavedSearchHistory(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method != http.MethodGet:
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			case req.RequestURI != "/services/saved/searches/errors/history?count=0&output_mode=json":
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			rw.Write([]byte(`{"entry":[
+				{"name":"sid_old","published":"2020-09-13T10:00:00-07:00","content":{"isScheduled":true,"isDone":true}},
+				{"name":"sid_manual","published":"2020-09-13T12:30:00-07:00","content":{"isScheduled":false,"isDone":true}},
+				{"name":"sid_new","published":"2020-09-13T12:00:00-07:00","content":{"isScheduled":true,"isDone":true}}
+			]}`))
+		}))
+		defer server.Close()
+		client := &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		}
+		runs, err := client.GetSavedSearchHistory(context.Background(), "errors")
+		require.NoError(t, err)
+		require.Len(t, runs, 3)
+		require.Equal(t, "sid_manual", runs[0].SearchID)
+		require.Equal(t, "sid_new", runs[1].SearchID)
+		require.True(t, runs[1].SearchContent.IsScheduled)
+		require.Equal(t, "sid_old", runs[2].SearchID)
+	})
+}
@@ -108,6 +108,7 @@ type SearchContent struct {
 	IsRemoteTimeline                  bool          `json:"isRemoteTimeline"`
 	IsSaved                           bool          `json:"isSaved"`
 	IsSavedSearch                     bool          `json:"isSavedSearch"`
+	IsScheduled                       bool          `json:"isScheduled"`
 	IsTimeCursored                    bool          `json:"isTimeCursored"`
 	IsZombie                          bool          `json:"isZombie"`
 	Keywords                          string        `json:"keywords"`
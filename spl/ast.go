@@ -0,0 +1,190 @@
+package spl
+
+import (
+	"strings"
+)
+
+// Node is an argument of a command
+type Node interface {
+	// Pos Returns the byte offset of the node in the query
+	Pos() int
+	// String Returns the node as SPL
+	String() string
+}
+
+// Query is a pipeline of commands
+type Query struct {
+	Commands []*Command
+}
+
+// Command is a single command of a pipeline, such as "stats count by host"
+type Command struct {
+	// Lower case name of the command, "search" for the search terms at the start of a query.
+	// It is empty for a macro used as a command, the macro is the first argument
+	Name string
+	// The search at the start of a query without the "search" command name
+	Implicit bool
+	Args     []Node
+	Position int
+}
+
+// Term is a bare word or quoted string
+type Term struct {
+	// The value without quotes or escapes
+	Value    string
+	Quoted   bool
+	Position int
+}
+
+// Comparison is a field compared to a value, such as index=main or count>5.  Command options such as limit=10 are also comparisons
+type Comparison struct {
+	Field    string
+	Operator string
+	Value    Node
+	Position int
+}
+
+// Group is a list of nodes in parentheses
+type Group struct {
+	Nodes    []Node
+	Position int
+}
+
+// Function is a function call, such as if(x>1, "a", "b")
+type Function struct {
+	Name string
+	// The arguments including the comma Operators between them
+	Args     []Node
+	Position int
+}
+
+// Subsearch is a query in square brackets
+type Subsearch struct {
+	Query    *Query
+	Position int
+}
+
+// Macro is a macro in backticks, such as `my_macro(a,b)`
+type Macro struct {
+	Name string
+	// Arguments as written, empty if the macro has none
+	Args     []string
+	Position int
+}
+
+// Operator is a comma or a comparison that is not between a field and a value
+type Operator struct {
+	Value    string
+	Position int
+}
+
+// Pos Returns the byte offset of the node in the query
+func (n *Term) Pos() int { return n.Position }
+
+// Pos Returns the byte offset of the node in the query
+func (n *Comparison) Pos() int { return n.Position }
+
+// Pos Returns the byte offset of the node in the query
+func (n *Group) Pos() int { return n.Position }
+
+// Pos Returns the byte offset of the node in the query
+func (n *Function) Pos() int { return n.Position }
+
+// Pos Returns the byte offset of the node in the query
+func (n *Subsearch) Pos() int { return n.Position }
+
+// Pos Returns the byte offset of the node in the query
+func (n *Macro) Pos() int { return n.Position }
+
+// Pos Returns the byte offset of the node in the query
+func (n *Operator) Pos() int { return n.Position }
+
+// String Returns the query as SPL.  A query starting with a generating command starts with "|"
+func (q *Query) String() string {
+	commands := make([]string, len(q.Commands))
+	for i, command := range q.Commands {
+		commands[i] = command.String()
+	}
+	query := strings.Join(commands, " | ")
+	if len(q.Commands) > 0 && !q.Commands[0].Implicit {
+		query = "| " + query
+	}
+	return query
+}
+
+// String Returns the command as SPL
+func (c *Command) String() string {
+	args := joinNodes(c.Args)
+	if c.Implicit {
+		return args
+	}
+	if args == "" || c.Name == "" {
+		return c.Name + args
+	}
+	return c.Name + " " + args
+}
+
+// String Returns the term as SPL, quoted if it was quoted
+func (n *Term) String() string {
+	if n.Quoted {
+		return quoteString(n.Value)
+	}
+	return n.Value
+}
+
+// quoteString Returns the value as a double quoted SPL string, the same as splunk.QuoteString without importing the client
+func quoteString(value string) string {
+	escaped := strings.Replace(value, `\`, `\\`, -1)
+	escaped = strings.Replace(escaped, `"`, `\"`, -1)
+	return `"` + escaped + `"`
+}
+
+// String Returns the comparison as SPL
+func (n *Comparison) String() string {
+	return n.Field + n.Operator + n.Value.String()
+}
+
+// String Returns the group as SPL
+func (n *Group) String() string {
+	return "(" + joinNodes(n.Nodes) + ")"
+}
+
+// String Returns the function call as SPL
+func (n *Function) String() string {
+	return n.Name + "(" + joinNodes(n.Args) + ")"
+}
+
+// String Returns the subsearch as SPL
+func (n *Subsearch) String() string {
+	query := n.Query.String()
+	if len(n.Query.Commands) > 0 && n.Query.Commands[0].Implicit {
+		// Subsearches start with the search command
+		query = "search " + query
+	}
+	return "[" + query + "]"
+}
+
+// String Returns the macro as SPL
+func (n *Macro) String() string {
+	if len(n.Args) == 0 {
+		return "`" + n.Name + "`"
+	}
+	return "`" + n.Name + "(" + strings.Join(n.Args, ",") + ")`"
+}
+
+// String Returns the operator
+func (n *Operator) String() string {
+	return n.Value
+}
+
+// joinNodes Joins the nodes with spaces, without a space before commas
+func joinNodes(nodes []Node) string {
+	builder := strings.Builder{}
+	for i, node := range nodes {
+		if operator, ok := node.(*Operator); i > 0 && !(ok && operator.Value == ",") {
+			builder.WriteString(" ")
+		}
+		builder.WriteString(node.String())
+	}
+	return builder.String()
+}
@@ -0,0 +1,227 @@
+// Package spl parses splunk search queries into an AST, to check, rewrite and inspect them before they are run.
+//
+//	q, err := spl.Parse(`error | stats count by host`)
+//	err = q.InjectIndex("main")
+//	search, err := client.CreateSearchJob(ctx, q.String(), nil)
+//
+// CreateSearchJob prepends "search", so only pass it queries that start with a search command.
+// Query.String starts with "| " for a generating command such as tstats, which InjectIndex rejects.
+package spl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyntaxError is an error parsing a query
+type SyntaxError struct {
+	// Byte offset of the error in the query
+	Pos     int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %d: %s", e.Pos, e.Message)
+}
+
+// parser reads tokens into an AST
+type parser struct {
+	tokens []token
+	i      int
+}
+
+// Parse Parses the query into an AST.
+//
+// The query is the search as passed to CreateSearchJob, optionally starting with "search", or a pipeline starting with "|".
+// Commands are parsed generically: each argument is a term, comparison, group, function call, subsearch or macro.
+func Parse(query string) (*Query, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	q, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.typ != tokenEOF {
+		return nil, &SyntaxError{Pos: t.pos, Message: fmt.Sprintf("unexpected %q", t.value)}
+	}
+	return q, nil
+}
+
+// peek Returns the next token without consuming it
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+// next Consumes the next token
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.typ != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// parseQuery Parses a pipeline up to the end of the query or subsearch
+func (p *parser) parseQuery() (*Query, error) {
+	q := &Query{}
+
+	// The search at the start, unless it starts with a pipe
+	if t := p.peek(); t.typ != tokenPipe {
+		command := &Command{Name: "search", Implicit: true, Position: t.pos}
+		if t.typ == tokenWord && strings.EqualFold(t.value, "search") {
+			p.next()
+		}
+		args, err := p.parseNodes()
+		if err != nil {
+			return nil, err
+		}
+		command.Args = args
+		q.Commands = append(q.Commands, command)
+	}
+
+	for p.peek().typ == tokenPipe {
+		p.next()
+		t := p.peek()
+		command := &Command{Position: t.pos}
+		switch t.typ {
+		case tokenWord:
+			p.next()
+			command.Name = strings.ToLower(t.value)
+		case tokenMacro:
+			// A macro expanding to commands, it is the first argument of a command without a name
+		default:
+			return nil, &SyntaxError{Pos: t.pos, Message: "expected a command name after \"|\""}
+		}
+		args, err := p.parseNodes()
+		if err != nil {
+			return nil, err
+		}
+		command.Args = args
+		q.Commands = append(q.Commands, command)
+	}
+	return q, nil
+}
+
+// parseNodes Parses arguments up to the end of the command
+func (p *parser) parseNodes() ([]Node, error) {
+	nodes := []Node{}
+	for {
+		switch p.peek().typ {
+		case tokenEOF, tokenPipe, tokenRightBracket, tokenRightParen:
+			return nodes, nil
+		}
+		node, err := p.parseNode()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+}
+
+// parseNode Parses a single argument, a comparison if a value is followed by an operator
+func (p *parser) parseNode() (Node, error) {
+	t := p.peek()
+	if t.typ == tokenComma || t.typ == tokenOperator {
+		p.next()
+		return &Operator{Value: t.value, Position: t.pos}, nil
+	}
+
+	node, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	term, ok := node.(*Term)
+	if !ok || term.Quoted || p.peek().typ != tokenOperator {
+		return node, nil
+	}
+
+	operator := p.next()
+	switch p.peek().typ {
+	case tokenEOF, tokenPipe, tokenRightBracket, tokenRightParen, tokenComma, tokenOperator:
+		return nil, &SyntaxError{Pos: operator.pos, Message: fmt.Sprintf("expected a value after %q", operator.value)}
+	}
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	return &Comparison{Field: term.Value, Operator: operator.value, Value: value, Position: term.Position}, nil
+}
+
+// parseValue Parses a term, group, function call, subsearch or macro
+func (p *parser) parseValue() (Node, error) {
+	t := p.next()
+	switch t.typ {
+	case tokenWord:
+		if next := p.peek(); next.typ == tokenLeftParen && !next.spaced {
+			p.next()
+			args, err := p.parseNodes()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(tokenRightParen, next); err != nil {
+				return nil, err
+			}
+			return &Function{Name: t.value, Args: args, Position: t.pos}, nil
+		}
+		return &Term{Value: t.value, Position: t.pos}, nil
+	case tokenString:
+		return &Term{Value: t.value, Quoted: true, Position: t.pos}, nil
+	case tokenMacro:
+		return parseMacro(t)
+	case tokenLeftParen:
+		nodes, err := p.parseNodes()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenRightParen, t); err != nil {
+			return nil, err
+		}
+		return &Group{Nodes: nodes, Position: t.pos}, nil
+	case tokenLeftBracket:
+		q, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenRightBracket, t); err != nil {
+			return nil, err
+		}
+		if len(q.Commands) == 0 || (q.Commands[0].Implicit && len(q.Commands[0].Args) == 0 && len(q.Commands) == 1) {
+			return nil, &SyntaxError{Pos: t.pos, Message: "empty subsearch"}
+		}
+		return &Subsearch{Query: q, Position: t.pos}, nil
+	}
+	return nil, &SyntaxError{Pos: t.pos, Message: fmt.Sprintf("unexpected %q", t.value)}
+}
+
+// expect Consumes the closing token of open, or returns an error
+func (p *parser) expect(typ tokenType, open token) error {
+	if p.peek().typ != typ {
+		return &SyntaxError{Pos: open.pos, Message: fmt.Sprintf("unclosed %q", open.value)}
+	}
+	p.next()
+	return nil
+}
+
+// parseMacro Splits a macro into its name and arguments
+func parseMacro(t token) (Node, error) {
+	text := strings.TrimSpace(t.value)
+	macro := &Macro{Name: text, Position: t.pos}
+	if open := strings.IndexByte(text, '('); open >= 0 {
+		if !strings.HasSuffix(text, ")") {
+			return nil, &SyntaxError{Pos: t.pos, Message: "unclosed \"(\" in macro"}
+		}
+		macro.Name = strings.TrimSpace(text[:open])
+		if args := text[open+1 : len(text)-1]; strings.TrimSpace(args) != "" {
+			for _, arg := range strings.Split(args, ",") {
+				macro.Args = append(macro.Args, strings.TrimSpace(arg))
+			}
+		}
+	}
+	if macro.Name == "" {
+		return nil, &SyntaxError{Pos: t.pos, Message: "empty macro"}
+	}
+	return macro, nil
+}
@@ -0,0 +1,98 @@
+package spl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	q, err := Parse(`search index=main host!="web 1" error | eval x=if(count>=5, "a \"b\"", 'y') | stats count by host`)
+	require.NoError(t, err)
+	require.Len(t, q.Commands, 3)
+
+	search := q.Commands[0]
+	require.Equal(t, "search", search.Name)
+	require.True(t, search.Implicit)
+	require.Equal(t, []Node{
+		&Comparison{Field: "index", Operator: "=", Value: &Term{Value: "main", Position: 13}, Position: 7},
+		&Comparison{Field: "host", Operator: "!=", Value: &Term{Value: "web 1", Quoted: true, Position: 24}, Position: 18},
+		&Term{Value: "error", Position: 32},
+	}, search.Args)
+
+	eval := q.Commands[1]
+	require.Equal(t, "eval", eval.Name)
+	require.Len(t, eval.Args, 1)
+	function := eval.Args[0].(*Comparison).Value.(*Function)
+	require.Equal(t, "if", function.Name)
+	require.Len(t, function.Args, 5)
+	require.Equal(t, `a "b"`, function.Args[2].(*Term).Value)
+
+	require.Equal(t, `index=main host!="web 1" error | eval x=if(count>=5, "a \"b\"", 'y') | stats count by host`, q.String())
+}
+
+func TestParseSubsearchesAndMacros(t *testing.T) {
+	q, err := Parse("index=main [search index=users | fields user] `filter(a, b)` (x OR y) | lookup users user OUTPUT name")
+	require.NoError(t, err)
+
+	subsearch := q.Commands[0].Args[1].(*Subsearch)
+	require.Len(t, subsearch.Query.Commands, 2)
+	require.Equal(t, "fields", subsearch.Query.Commands[1].Name)
+
+	macro := q.Commands[0].Args[2].(*Macro)
+	require.Equal(t, "filter", macro.Name)
+	require.Equal(t, []string{"a", "b"}, macro.Args)
+
+	require.IsType(t, &Group{}, q.Commands[0].Args[3])
+	require.Equal(t, "index=main [search index=users | fields user] `filter(a,b)` (x OR y) | lookup users user OUTPUT name", q.String())
+
+	q, err = Parse("| inputlookup users.csv | where isnull(name)")
+	require.NoError(t, err)
+	require.False(t, q.Commands[0].Implicit)
+	require.Equal(t, "inputlookup", q.Commands[0].Name)
+	require.Equal(t, "| inputlookup users.csv | where isnull(name)", q.String())
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	for _, query := range []string{
+		`uri=/a?b=c`,
+		`uri=https://example.com/path?a=1&b=2 status>=500`,
+		`token=abc== signature=QUJD+/x=`,
+		`index=main filter=a<b host!=web=1 | stats count by uri`,
+		`| eval x=if(a==b, "y=z", 0)`,
+	} {
+		q, err := Parse(query)
+		require.NoError(t, err, query)
+		require.Equal(t, query, q.String())
+	}
+
+	// The whole value is the comparison value
+	q, err := Parse(`uri=/a?b=c`)
+	require.NoError(t, err)
+	require.Equal(t, []Node{
+		&Comparison{Field: "uri", Operator: "=", Value: &Term{Value: "/a?b=c", Position: 4}, Position: 0},
+	}, q.Commands[0].Args)
+}
+
+func TestParseErrors(t *testing.T) {
+	for query, pos := range map[string]int{
+		`index=main "unclosed`:         11,
+		"index=main `macro":            11,
+		"index=main | ":                13,
+		"index=main || stats count":    12,
+		"index=main [search x":         11,
+		"index=main []":                11,
+		"index=main (a OR b":           11,
+		"index=main )":                 11,
+		"index=main | eval x=":         19,
+		"index=main | rex \"(?<a>.)\"": -1,
+	} {
+		_, err := Parse(query)
+		if pos < 0 {
+			require.NoError(t, err, query)
+			continue
+		}
+		require.Error(t, err, query)
+		require.Equal(t, pos, err.(*SyntaxError).Pos, query)
+	}
+}
@@ -0,0 +1,209 @@
+package spl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lookupCommands are the commands whose first term is a lookup name
+var lookupCommands = map[string]bool{
+	"lookup":       true,
+	"inputlookup":  true,
+	"outputlookup": true,
+}
+
+// Walk Calls fn with every command of the query, including the commands of subsearches after the command containing them
+func (q *Query) Walk(fn func(command *Command)) {
+	for _, command := range q.Commands {
+		fn(command)
+		walkNodes(command.Args, func(node Node) {
+			if subsearch, ok := node.(*Subsearch); ok {
+				subsearch.Query.Walk(fn)
+			}
+		})
+	}
+}
+
+// walkNodes Calls fn with every node, including the nodes inside groups, function calls and comparisons, but not subsearches
+func walkNodes(nodes []Node, fn func(node Node)) {
+	for _, node := range nodes {
+		fn(node)
+		switch typedNode := node.(type) {
+		case *Group:
+			walkNodes(typedNode.Nodes, fn)
+		case *Function:
+			walkNodes(typedNode.Args, fn)
+		case *Comparison:
+			walkNodes([]Node{typedNode.Value}, fn)
+		}
+	}
+}
+
+// walkAllNodes Calls fn with every node of every command, including subsearches
+func (q *Query) walkAllNodes(fn func(command *Command, nodes []Node)) {
+	q.Walk(func(command *Command) {
+		fn(command, command.Args)
+		walkNodes(command.Args, func(node Node) {
+			switch typedNode := node.(type) {
+			case *Group:
+				fn(command, typedNode.Nodes)
+			case *Function:
+				fn(command, typedNode.Args)
+			}
+		})
+	})
+}
+
+// Indexes Returns the indexes the query references with index=name or index IN (names), in order without duplicates
+func (q *Query) Indexes() []string {
+	indexes := newNameSet()
+	q.walkAllNodes(func(command *Command, nodes []Node) {
+		for i, node := range nodes {
+			switch typedNode := node.(type) {
+			case *Comparison:
+				if strings.EqualFold(typedNode.Field, "index") && (typedNode.Operator == "=" || typedNode.Operator == "==") {
+					if term, ok := typedNode.Value.(*Term); ok {
+						indexes.add(term.Value)
+					}
+				}
+			case *Term:
+				// index IN (a, b)
+				if typedNode.Quoted || !strings.EqualFold(typedNode.Value, "index") || i+2 >= len(nodes) {
+					continue
+				}
+				in, ok := nodes[i+1].(*Term)
+				group, isGroup := nodes[i+2].(*Group)
+				if !ok || in.Quoted || !strings.EqualFold(in.Value, "IN") || !isGroup {
+					continue
+				}
+				for _, groupNode := range group.Nodes {
+					if term, ok := groupNode.(*Term); ok {
+						indexes.add(term.Value)
+					}
+				}
+			}
+		}
+	})
+	return indexes.names
+}
+
+// Lookups Returns the lookups used by the lookup, inputlookup and outputlookup commands, in order without duplicates
+func (q *Query) Lookups() []string {
+	lookups := newNameSet()
+	q.Walk(func(command *Command) {
+		if !lookupCommands[command.Name] {
+			return
+		}
+		// The first argument that is not an option such as append=true
+		for _, node := range command.Args {
+			if term, ok := node.(*Term); ok {
+				lookups.add(term.Value)
+				return
+			}
+		}
+	})
+	return lookups.names
+}
+
+// Macros Returns the names of the macros used, in order without duplicates
+func (q *Query) Macros() []string {
+	macros := newNameSet()
+	q.walkAllNodes(func(command *Command, nodes []Node) {
+		for _, node := range nodes {
+			if macro, ok := node.(*Macro); ok {
+				macros.add(macro.Name)
+			}
+		}
+	})
+	return macros.names
+}
+
+// AddTerms Adds search terms to the search at the start of the query, so they constrain every result.
+//
+// Existing terms joined with OR are grouped first.  It returns an error if the query starts with a generating command.
+func (q *Query) AddTerms(nodes ...Node) error {
+	if len(q.Commands) == 0 || !q.Commands[0].Implicit {
+		return fmt.Errorf("query does not start with a search")
+	}
+	search := q.Commands[0]
+	for _, node := range search.Args {
+		if term, ok := node.(*Term); ok && !term.Quoted && term.Value == "OR" {
+			search.Args = []Node{&Group{Nodes: search.Args, Position: search.Position}}
+			break
+		}
+	}
+	search.Args = append(search.Args, nodes...)
+	return nil
+}
+
+// InjectIndex Constrains the search at the start of the query to the indexes
+func (q *Query) InjectIndex(indexes ...string) error {
+	if len(indexes) == 0 {
+		return fmt.Errorf("no index")
+	}
+	nodes := []Node{}
+	for i, index := range indexes {
+		if i > 0 {
+			nodes = append(nodes, &Term{Value: "OR"})
+		}
+		nodes = append(nodes, &Comparison{Field: "index", Operator: "=", Value: &Term{Value: index, Quoted: true}})
+	}
+	if len(nodes) == 1 {
+		return q.AddTerms(nodes[0])
+	}
+	return q.AddTerms(&Group{Nodes: nodes})
+}
+
+// InjectTimeRange Sets the earliest and latest time modifiers of the search at the start of the query, replacing any it has.
+// An empty modifier is not set.
+func (q *Query) InjectTimeRange(earliest, latest string) error {
+	if len(q.Commands) == 0 || !q.Commands[0].Implicit {
+		return fmt.Errorf("query does not start with a search")
+	}
+	search := q.Commands[0]
+	search.Args = removeTimeModifiers(search.Args)
+
+	nodes := []Node{}
+	if earliest != "" {
+		nodes = append(nodes, &Comparison{Field: "earliest", Operator: "=", Value: &Term{Value: earliest, Quoted: true}})
+	}
+	if latest != "" {
+		nodes = append(nodes, &Comparison{Field: "latest", Operator: "=", Value: &Term{Value: latest, Quoted: true}})
+	}
+	return q.AddTerms(nodes...)
+}
+
+// removeTimeModifiers Returns the nodes without earliest and latest comparisons, including those in groups
+func removeTimeModifiers(nodes []Node) []Node {
+	kept := []Node{}
+	for _, node := range nodes {
+		switch typedNode := node.(type) {
+		case *Comparison:
+			if strings.EqualFold(typedNode.Field, "earliest") || strings.EqualFold(typedNode.Field, "latest") {
+				continue
+			}
+		case *Group:
+			typedNode.Nodes = removeTimeModifiers(typedNode.Nodes)
+		}
+		kept = append(kept, node)
+	}
+	return kept
+}
+
+// nameSet is a list of names without duplicates
+type nameSet struct {
+	names []string
+	seen  map[string]bool
+}
+
+func newNameSet() *nameSet {
+	return &nameSet{names: []string{}, seen: map[string]bool{}}
+}
+
+// add Adds the name if it is not in the set
+func (s *nameSet) add(name string) {
+	if !s.seen[name] {
+		s.seen[name] = true
+		s.names = append(s.names, name)
+	}
+}
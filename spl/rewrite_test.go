@@ -0,0 +1,44 @@
+package spl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery_Extract(t *testing.T) {
+	q, err := Parse("index=main OR index=\"web\" [search index IN (users, main) | `fields_macro`] | lookup geo ip | join user [| inputlookup append=true admins.csv] | outputlookup results")
+	require.NoError(t, err)
+	require.Equal(t, []string{"main", "web", "users"}, q.Indexes())
+	require.Equal(t, []string{"geo", "admins.csv", "results"}, q.Lookups())
+	require.Equal(t, []string{"fields_macro"}, q.Macros())
+
+	names := []string{}
+	q.Walk(func(command *Command) {
+		names = append(names, command.Name)
+	})
+	require.Equal(t, []string{"search", "search", "", "lookup", "join", "inputlookup", "outputlookup"}, names)
+}
+
+func TestQuery_Inject(t *testing.T) {
+	q, err := Parse("error OR warn earliest=-7d | stats count")
+	require.NoError(t, err)
+	require.NoError(t, q.InjectIndex("main", "web"))
+	require.NoError(t, q.InjectTimeRange("-24h@h", "now"))
+	require.Equal(t, `(error OR warn) (index="main" OR index="web") earliest="-24h@h" latest="now" | stats count`, q.String())
+
+	q, err = Parse("error | stats count")
+	require.NoError(t, err)
+	require.NoError(t, q.InjectTimeRange("-1h", ""))
+	require.NoError(t, q.InjectIndex(`a"b`))
+	require.Equal(t, `error earliest="-1h" index="a\"b" | stats count`, q.String())
+
+	q, err = Parse("uri=/a?b=c token=abc== | stats count")
+	require.NoError(t, err)
+	require.NoError(t, q.InjectIndex("main"))
+	require.Equal(t, `uri=/a?b=c token=abc== index="main" | stats count`, q.String())
+
+	q, err = Parse("| tstats count")
+	require.NoError(t, err)
+	require.Error(t, q.InjectIndex("main"))
+}
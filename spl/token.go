@@ -0,0 +1,145 @@
+package spl
+
+import (
+	"strings"
+)
+
+// tokenType is the kind of a token
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	// A bare word, such as a command name, field or value
+	tokenWord
+	// A double quoted string, its value is unescaped
+	tokenString
+	// A macro between backticks, its value is the text inside
+	tokenMacro
+	tokenPipe
+	tokenLeftBracket
+	tokenRightBracket
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+	// A comparison, one of = == != < <= > >=
+	tokenOperator
+)
+
+// token is a single token of a query
+type token struct {
+	typ   tokenType
+	value string
+	// Byte offset of the token in the query
+	pos int
+	// Whether the token follows whitespace, used to tell a function call "if(" from a group "if ("
+	spaced bool
+}
+
+// wordBreaks are the characters that end a bare word
+const wordBreaks = "|[]()\",`"
+
+// tokenize Splits the query into tokens, ending with tokenEOF
+func tokenize(query string) ([]token, error) {
+	tokens := []token{}
+	spaced := true
+	// Whether the last token is a comparison operator right before this one.  The value of a comparison
+	// runs to the next space or word break, so values such as uri=/a?b=c or token=abc== stay whole
+	afterOperator := false
+	for i := 0; i < len(query); {
+		c := query[i]
+		if isSpace(c) {
+			spaced = true
+			i++
+			continue
+		}
+
+		t := token{pos: i, spaced: spaced}
+		valueStart := afterOperator && !spaced
+		spaced = false
+		switch {
+		case c == '|':
+			t.typ, t.value = tokenPipe, "|"
+			i++
+		case c == '[':
+			t.typ, t.value = tokenLeftBracket, "["
+			i++
+		case c == ']':
+			t.typ, t.value = tokenRightBracket, "]"
+			i++
+		case c == '(':
+			t.typ, t.value = tokenLeftParen, "("
+			i++
+		case c == ')':
+			t.typ, t.value = tokenRightParen, ")"
+			i++
+		case c == ',':
+			t.typ, t.value = tokenComma, ","
+			i++
+		case c == '"':
+			value, end, err := readString(query, i)
+			if err != nil {
+				return nil, err
+			}
+			t.typ, t.value = tokenString, value
+			i = end
+		case c == '`':
+			end := strings.IndexByte(query[i+1:], '`')
+			if end < 0 {
+				return nil, &SyntaxError{Pos: i, Message: "unterminated macro"}
+			}
+			t.typ, t.value = tokenMacro, query[i+1:i+1+end]
+			i += end + 2
+		case isOperatorStart(query, i) && !valueStart:
+			operator := query[i : i+1]
+			if i+1 < len(query) && query[i+1] == '=' {
+				operator = query[i : i+2]
+			}
+			t.typ, t.value = tokenOperator, operator
+			i += len(operator)
+		default:
+			end := i
+			for end < len(query) && !isSpace(query[end]) && !strings.ContainsRune(wordBreaks, rune(query[end])) && (valueStart || !isOperatorStart(query, end)) {
+				end++
+			}
+			t.typ, t.value = tokenWord, query[i:end]
+			i = end
+		}
+		afterOperator = t.typ == tokenOperator
+		tokens = append(tokens, t)
+	}
+	return append(tokens, token{typ: tokenEOF, pos: len(query), spaced: spaced}), nil
+}
+
+// readString Reads the double quoted string starting at start, returning its unescaped value and the offset after it
+func readString(query string, start int) (string, int, error) {
+	value := strings.Builder{}
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if i+1 < len(query) && (query[i+1] == '"' || query[i+1] == '\\') {
+				i++
+			}
+			value.WriteByte(query[i])
+		case '"':
+			return value.String(), i + 1, nil
+		default:
+			value.WriteByte(query[i])
+		}
+	}
+	return "", 0, &SyntaxError{Pos: start, Message: "unterminated string"}
+}
+
+// isOperatorStart Returns whether a comparison starts at i.  A "!" is only a comparison in "!="
+func isOperatorStart(query string, i int) bool {
+	switch query[i] {
+	case '=', '<', '>':
+		return true
+	case '!':
+		return i+1 < len(query) && query[i+1] == '='
+	}
+	return false
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
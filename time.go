@@ -1,12 +1,46 @@
 package splunk
 
-import "time"
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
 
 const (
 	// SplunkTimeFormat is the time format splunk expects from time parameters
 	SplunkTimeFormat = "2006-01-02T15:04:05.000-07:00"
+
+	// EpochTimeFormat is the timeformat tag option to parse and format times as epoch seconds
+	EpochTimeFormat = "epoch"
 )
 
+// timeLayouts are the layouts ParseAnyTime tries in order.
+// Fractional seconds are accepted after the seconds of any layout.
+var timeLayouts = []string{
+	SplunkTimeFormat,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"01/02/2006 15:04:05",
+	"01/02/2006:15:04:05",
+	time.RFC1123Z,
+	time.RFC1123,
+	"20060102150405",
+	"2006-01-02",
+	"20060102",
+}
+
+// epochRegexp matches the strings ParseAnyTime treats as epoch seconds, so compact dates such as "20200913" are not
+var epochRegexp = regexp.MustCompile(`^\d{9,10}(\.\d+)?$`)
+
 // FormatTime Will format a time correct to be sent as a parameter
 func FormatTime(time time.Time) string {
 	return time.UTC().Format(SplunkTimeFormat)
@@ -16,3 +50,83 @@ func FormatTime(time time.Time) string {
 func ParseTime(timeString string) (time.Time, error) {
 	return time.Parse(SplunkTimeFormat, timeString)
 }
+
+// ParseEpochTime Parses epoch seconds with an optional fraction, such as _time or _indextime, ex: "1600000000.123"
+func ParseEpochTime(epoch string) (time.Time, error) {
+	epoch = strings.TrimSpace(epoch)
+	parts := strings.SplitN(epoch, ".", 2)
+	seconds, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || strings.HasPrefix(epoch, "-") {
+		// Fall back to a float for anything unusual
+		floatValue, floatErr := strconv.ParseFloat(epoch, 64)
+		if floatErr != nil || math.IsNaN(floatValue) || math.IsInf(floatValue, 0) {
+			return time.Time{}, fmt.Errorf("not an epoch time: %s", epoch)
+		}
+		whole, fraction := math.Modf(floatValue)
+		return time.Unix(int64(whole), int64(fraction*float64(time.Second))), nil
+	}
+
+	nanoseconds := int64(0)
+	if len(parts) == 2 && parts[1] != "" {
+		fraction := parts[1]
+		for i := 0; i < len(fraction); i++ {
+			if !isDigit(fraction[i]) {
+				return time.Time{}, fmt.Errorf("not an epoch time: %s", epoch)
+			}
+		}
+		if len(fraction) > 9 {
+			fraction = fraction[:9]
+		}
+		fraction += strings.Repeat("0", 9-len(fraction))
+		nanoseconds, err = strconv.ParseInt(fraction, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("not an epoch time: %s", epoch)
+		}
+	}
+	return time.Unix(seconds, nanoseconds), nil
+}
+
+// ParseAnyTime Parses a time in any of the common formats splunk returns.
+//
+// It accepts epoch seconds (as a number, or a string of 9 or 10 digits with an optional fraction), SplunkTimeFormat
+// with any fractional seconds, ISO 8601 with a "Z" or numeric zone, compact dates such as "20200913" or "20200913122640",
+// and the common layouts splunk displays times in.
+// Times without a zone are parsed as UTC.
+func ParseAnyTime(value interface{}) (time.Time, error) {
+	switch typedValue := value.(type) {
+	case float64:
+		return ParseEpochTime(strconv.FormatFloat(typedValue, 'f', -1, 64))
+	case time.Time:
+		return typedValue, nil
+	}
+
+	timeString := strings.TrimSpace(fmt.Sprintf("%v", value))
+	if epochRegexp.MatchString(timeString) {
+		return ParseEpochTime(timeString)
+	}
+	for _, layout := range timeLayouts {
+		if parsedTime, err := time.Parse(layout, timeString); err == nil {
+			return parsedTime, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown time format: %s", timeString)
+}
+
+// parseTimeFormat Parses the value with the layout of a timeformat tag option
+func parseTimeFormat(value interface{}, layout string) (time.Time, error) {
+	if layout == EpochTimeFormat {
+		if floatValue, ok := value.(float64); ok {
+			return ParseEpochTime(strconv.FormatFloat(floatValue, 'f', -1, 64))
+		}
+		return ParseEpochTime(fmt.Sprintf("%v", value))
+	}
+	return time.Parse(layout, fmt.Sprintf("%v", value))
+}
+
+// formatTimeFormat Formats the time with the layout of a timeformat tag option
+func formatTimeFormat(t time.Time, layout string) interface{} {
+	if layout == EpochTimeFormat {
+		return float64(t.UnixNano()) / float64(time.Second)
+	}
+	return t.Format(layout)
+}
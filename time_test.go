@@ -0,0 +1,87 @@
+package splunk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEpochTime(t *testing.T) {
+	parsed, err := ParseEpochTime("1600000000.123")
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1600000000, 123000000), parsed)
+
+	parsed, err = ParseEpochTime("1600000000")
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1600000000, 0), parsed)
+
+	for _, value := range []string{"2020-09-13", "1600000000.-5", "1600000000.5e3", "1600000000.+5"} {
+		_, err = ParseEpochTime(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestParseAnyTime(t *testing.T) {
+	expected := time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC)
+	for _, value := range []interface{}{
+		"2020-09-13T12:26:40.000+00:00",
+		"2020-09-13T12:26:40.000000+00:00",
+		"2020-09-13T14:26:40.000+02:00",
+		"2020-09-13T12:26:40Z",
+		"2020-09-13T12:26:40.000Z",
+		"2020-09-13T12:26:40+0000",
+		"2020-09-13 12:26:40 +0000",
+		"2020-09-13 12:26:40",
+		"09/13/2020 12:26:40",
+		"1600000000",
+		"1600000000.000",
+		float64(1600000000),
+	} {
+		parsed, err := ParseAnyTime(value)
+		require.NoError(t, err, "%v", value)
+		require.True(t, expected.Equal(parsed), "%v parsed as %s", value, parsed)
+	}
+
+	// Compact dates are not epoch seconds
+	parsed, err := ParseAnyTime("20200913122640")
+	require.NoError(t, err)
+	require.True(t, expected.Equal(parsed), "parsed as %s", parsed)
+	parsed, err = ParseAnyTime("20200913")
+	require.NoError(t, err)
+	require.True(t, time.Date(2020, 9, 13, 0, 0, 0, 0, time.UTC).Equal(parsed), "parsed as %s", parsed)
+
+	for _, value := range []string{"yesterday", "1600000000.-5", "1600000000000"} {
+		_, err = ParseAnyTime(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestUnMarshalTimeFormat(t *testing.T) {
+	type TestStruct struct {
+		Time      time.Time  `splunk:"_time"`
+		IndexTime time.Time  `splunk:"_indextime,timeformat=epoch"`
+		Created   time.Time  `splunk:"created,timeformat=Jan 2, 2006"`
+		Updated   *time.Time `splunk:"updated"`
+	}
+
+	testStruct := TestStruct{}
+	err := SearchResult{
+		"_time":      "2020-09-13T12:26:40.500Z",
+		"_indextime": "1600000001",
+		"created":    "Sep 13, 2020",
+		"updated":    float64(1600000002),
+	}.UnMarshal(&testStruct)
+	require.NoError(t, err)
+	require.True(t, time.Date(2020, 9, 13, 12, 26, 40, 500000000, time.UTC).Equal(testStruct.Time))
+	require.True(t, time.Unix(1600000001, 0).Equal(testStruct.IndexTime))
+	require.True(t, time.Date(2020, 9, 13, 0, 0, 0, 0, time.UTC).Equal(testStruct.Created))
+	require.True(t, time.Unix(1600000002, 0).Equal(*testStruct.Updated))
+
+	require.Error(t, SearchResult{"created": "2020-09-13"}.UnMarshal(&testStruct))
+
+	event, err := MarshalEvent(TestStruct{IndexTime: time.Unix(1600000001, 0), Created: time.Date(2020, 9, 13, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	require.Equal(t, float64(1600000001), event.Event.(map[string]interface{})["_indextime"])
+	require.Equal(t, "Sep 13, 2020", event.Event.(map[string]interface{})["created"])
+}
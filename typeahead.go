@@ -0,0 +1,65 @@
+package splunk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+const (
+	searchTypeaheadSuffix = "/services/search/typeahead"
+
+	defaultTypeaheadCount = 10
+)
+
+// TypeaheadSuggestion is a completion of a search prefix
+type TypeaheadSuggestion struct {
+	// The completed search term, such as "sourcetype=access_combined"
+	Content string `json:"content"`
+	// Number of events matching the term
+	Count int64 `json:"count"`
+	// Whether the suggestion is an operator such as OR instead of a term
+	Operator bool `json:"operator"`
+}
+
+// typeaheadResponse is what splunk returns from the typeahead endpoint
+type typeaheadResponse struct {
+	Results []TypeaheadSuggestion `json:"results"`
+}
+
+// Typeahead Gets up to count completions of the search prefix, most common first.  A count of 0 uses the default of 10.
+//
+// Params are any other parameters you want to specific from [the documentation](https://docs.splunk.com/Documentation/Splunk/8.0.5/RESTREF/RESTsearch#search.2Ftypeahead)
+func (c *Client) Typeahead(ctx context.Context, prefix string, count int, params map[string]string) ([]TypeaheadSuggestion, error) {
+	if count <= 0 {
+		count = defaultTypeaheadCount
+	}
+	paramsToSend := map[string]string{}
+	for key, value := range params {
+		paramsToSend[key] = value
+	}
+	paramsToSend["prefix"] = prefix
+	paramsToSend["count"] = strconv.Itoa(count)
+
+	resp, err := c.BuildResponse(ctx, http.MethodGet, searchTypeaheadSuffix, paramsToSend)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("bad status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	result := typeaheadResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %s", err)
+	}
+	if result.Results == nil {
+		result.Results = []TypeaheadSuggestion{}
+	}
+	return result.Results, nil
+}
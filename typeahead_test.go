@@ -0,0 +1,41 @@
+package splunk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Typeahead(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method != http.MethodGet:
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		case req.URL.Path != searchTypeaheadSuffix:
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		case req.URL.Query().Get("prefix") != "sourcetype=a" || req.URL.Query().Get("count") != "10":
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw.Write([]byte(`{"results":[{"content":"sourcetype=access_combined","count":1200,"operator":false},{"content":"sourcetype=apache_error","count":30,"operator":false}]}`))
+	}))
+	defer server.Close()
+	client := &Client{
+		config: &Config{
+			BaseURL:    server.URL,
+			HTTPClient: http.DefaultClient,
+		},
+	}
+
+	suggestions, err := client.Typeahead(context.Background(), "sourcetype=a", 0, nil)
+	require.NoError(t, err)
+	require.Equal(t, []TypeaheadSuggestion{
+		{Content: "sourcetype=access_combined", Count: 1200},
+		{Content: "sourcetype=apache_error", Count: 30},
+	}, suggestions)
+}
@@ -0,0 +1,373 @@
+package splunk
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWindowConcurrency = 4
+	// Number of buckets counted by the pre-search of adaptive windows
+	defaultWindowDensityBuckets = 100
+)
+
+// TimeWindow is a range of time to search, from Earliest up to but not including Latest
+type TimeWindow struct {
+	Earliest time.Time
+	Latest   time.Time
+}
+
+// SplitTimeRange Splits the range into windows of the given size.  The last window is shorter if the range does not divide evenly
+func SplitTimeRange(earliest, latest time.Time, size time.Duration) []TimeWindow {
+	windows := []TimeWindow{}
+	if size <= 0 || !earliest.Before(latest) {
+		return windows
+	}
+	for start := earliest; start.Before(latest); start = start.Add(size) {
+		end := start.Add(size)
+		if end.After(latest) {
+			end = latest
+		}
+		windows = append(windows, TimeWindow{Earliest: start, Latest: end})
+	}
+	return windows
+}
+
+// SplitTimeRangeN Splits the range into n windows of equal size
+func SplitTimeRangeN(earliest, latest time.Time, n int) []TimeWindow {
+	if n <= 0 || !earliest.Before(latest) {
+		return []TimeWindow{}
+	}
+	size := latest.Sub(earliest) / time.Duration(n)
+	if size <= 0 {
+		size = 1
+	}
+	windows := SplitTimeRange(earliest, latest, size)
+	if len(windows) > n {
+		// Fold the rounding remainder into the last window
+		windows[n-1].Latest = latest
+		windows = windows[:n]
+	}
+	return windows
+}
+
+// densityBucket is the number of events in a window of time
+type densityBucket struct {
+	window TimeWindow
+	count  int64
+}
+
+// adaptiveWindows Joins consecutive buckets into windows of at most maxEvents events.
+// A single bucket with more than maxEvents events is its own window.
+func adaptiveWindows(buckets []densityBucket, maxEvents int64) []TimeWindow {
+	windows := []TimeWindow{}
+	var current *TimeWindow
+	currentCount := int64(0)
+	for _, bucket := range buckets {
+		if current != nil && currentCount+bucket.count <= maxEvents {
+			current.Latest = bucket.window.Latest
+			currentCount += bucket.count
+			continue
+		}
+		if current != nil {
+			windows = append(windows, *current)
+		}
+		window := bucket.window
+		current = &window
+		currentCount = bucket.count
+	}
+	if current != nil {
+		windows = append(windows, *current)
+	}
+	return windows
+}
+
+// WindowedSearchOptions are the options to run a search split into time windows.
+//
+// Set one of WindowSize, Windows or MaxEventsPerWindow to choose how the range is split.
+type WindowedSearchOptions struct {
+	// Time range to search
+	Earliest time.Time
+	Latest   time.Time
+
+	// Split the range into windows of this size
+	WindowSize time.Duration
+	// Split the range into this many windows of equal size
+	Windows int
+	// Split the range into windows with at most this many events, using a pre-search to count the events over time
+	MaxEventsPerWindow int64
+
+	// Maximum number of jobs running at once.  Default: 4
+	Concurrency int
+	// Params are any other parameters for each job, see CreateSearchJob
+	Params map[string]string
+}
+
+// WindowedSearch is a search split into time windows running as separate jobs
+type WindowedSearch struct {
+	// The windows being searched, oldest first
+	Windows []TimeWindow
+
+	results chan SearchResult
+	lock    sync.Mutex
+	err     error
+}
+
+// Results Returns the channel of results from every window, oldest first.  Check Err after it is closed
+func (w *WindowedSearch) Results() chan SearchResult {
+	return w.results
+}
+
+// Err Returns the first error from the search, once Results is closed
+func (w *WindowedSearch) Err() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.err
+}
+
+// setErr records the first error of the search
+func (w *WindowedSearch) setErr(err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// CreateWindowedSearch Splits a large time range into windows and searches them as parallel jobs, at most Concurrency at once.
+//
+// The results of each window are sorted by _time, and the windows are sent in order, so the results are merged oldest first.
+// A window keeps its slot until its results are sent, so a slow reader also slows the jobs, and at most Concurrency windows
+// are held in memory.  The jobs are deleted once their results are read.
+//
+// This is meant for event searches.  Transforming commands such as stats would be run separately for each window.
+func (c *Client) CreateWindowedSearch(ctx context.Context, query string, options *WindowedSearchOptions) (*WindowedSearch, error) {
+	if !options.Earliest.Before(options.Latest) {
+		return nil, fmt.Errorf("earliest must be before latest")
+	}
+
+	var windows []TimeWindow
+	switch {
+	case options.WindowSize > 0:
+		windows = SplitTimeRange(options.Earliest, options.Latest, options.WindowSize)
+	case options.Windows > 0:
+		windows = SplitTimeRangeN(options.Earliest, options.Latest, options.Windows)
+	case options.MaxEventsPerWindow > 0:
+		buckets, err := c.countEventDensity(ctx, query, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count events: %s", err)
+		}
+		windows = adaptiveWindows(buckets, options.MaxEventsPerWindow)
+	default:
+		return nil, fmt.Errorf("one of WindowSize, Windows or MaxEventsPerWindow must be set")
+	}
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultWindowConcurrency
+	}
+
+	w := &WindowedSearch{
+		Windows: windows,
+		results: make(chan SearchResult, 400),
+	}
+
+	// Search the windows in parallel, each one delivers its sorted results on its own channel.
+	// Windows take a slot in order, and keep it until their results are sent, so at most concurrency windows are held in memory
+	searchContext, cancel := context.WithCancel(ctx)
+	windowResults := make([]chan []SearchResult, len(windows))
+	for i := range windows {
+		windowResults[i] = make(chan []SearchResult, 1)
+	}
+	semaphore := make(chan struct{}, concurrency)
+	go func() {
+		for i, window := range windows {
+			select {
+			case semaphore <- struct{}{}:
+			case <-searchContext.Done():
+				for _, out := range windowResults[i:] {
+					close(out)
+				}
+				return
+			}
+			go func(window TimeWindow, out chan []SearchResult) {
+				defer close(out)
+				results, err := c.searchWindow(searchContext, query, window, options.Params)
+				if err != nil {
+					<-semaphore
+					w.setErr(err)
+					cancel()
+					return
+				}
+				out <- results
+			}(window, windowResults[i])
+		}
+	}()
+
+	// Send the windows in order
+	go func() {
+		defer close(w.results)
+		defer cancel()
+		for _, out := range windowResults {
+			results, ok := <-out
+			if !ok {
+				// The window failed or the search was canceled
+				w.setErr(searchContext.Err())
+				return
+			}
+			for _, result := range results {
+				select {
+				case w.results <- result:
+				case <-searchContext.Done():
+					w.setErr(searchContext.Err())
+					return
+				}
+			}
+			// Let the next window start
+			<-semaphore
+		}
+	}()
+
+	return w, nil
+}
+
+// searchWindow Runs the query over the window and returns its results sorted by _time
+func (c *Client) searchWindow(ctx context.Context, query string, window TimeWindow, params map[string]string) ([]SearchResult, error) {
+	search, err := c.createWindowJob(ctx, query, window, params)
+	if err != nil {
+		return nil, err
+	}
+	defer search.Delete(context.Background())
+
+	if err := search.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("failed waiting on window %s: %s", window, err)
+	}
+	results, err := search.GetResults(ctx)
+	if err != nil {
+		return nil, err
+	}
+	windowResults := []SearchResult{}
+	for result := range results {
+		windowResults = append(windowResults, result)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	sortResultsByTime(windowResults)
+	return windowResults, nil
+}
+
+// createWindowJob Creates a search job over the window
+func (c *Client) createWindowJob(ctx context.Context, query string, window TimeWindow, params map[string]string) (*Search, error) {
+	jobParams := map[string]string{}
+	for key, value := range params {
+		jobParams[key] = value
+	}
+	jobParams["earliest_time"] = FormatTime(window.Earliest)
+	jobParams["latest_time"] = FormatTime(window.Latest)
+
+	search, err := c.CreateSearchJob(ctx, query, jobParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job for window %s: %s", window, err)
+	}
+	return search, nil
+}
+
+// sortResultsByTime Sorts the results oldest first by _time.  Results without a valid _time keep their order at the start
+func sortResultsByTime(results []SearchResult) {
+	times := make(map[int]time.Time, len(results))
+	indexes := make([]int, len(results))
+	for i, result := range results {
+		indexes[i] = i
+		if parsedTime, err := ParseAnyTime(result["_time"]); err == nil {
+			times[i] = parsedTime
+		}
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return times[indexes[i]].Before(times[indexes[j]])
+	})
+
+	sorted := make([]SearchResult, len(results))
+	for i, index := range indexes {
+		sorted[i] = results[index]
+	}
+	copy(results, sorted)
+}
+
+// countEventDensity Runs a pre-search counting the events of the query over the time range in buckets
+func (c *Client) countEventDensity(ctx context.Context, query string, options *WindowedSearchOptions) ([]densityBucket, error) {
+	totalRange := options.Latest.Sub(options.Earliest)
+	span := totalRange / defaultWindowDensityBuckets
+	if span < time.Second {
+		span = time.Second
+	}
+	// Round up to whole seconds so the buckets cover the range
+	span = (span + time.Second - 1) / time.Second * time.Second
+
+	countQuery := fmt.Sprintf("%s | timechart span=%ds count", query, int64(span/time.Second))
+	search, err := c.createWindowJob(ctx, countQuery, TimeWindow{Earliest: options.Earliest, Latest: options.Latest}, options.Params)
+	if err != nil {
+		return nil, err
+	}
+	defer search.Delete(context.Background())
+	if err := search.Wait(ctx); err != nil {
+		return nil, err
+	}
+	results, err := search.GetResults(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := []densityBucket{}
+	for result := range results {
+		bucketTime, err := ParseAnyTime(result["_time"])
+		if err != nil {
+			return nil, fmt.Errorf("bad bucket time: %v", result["_time"])
+		}
+		count, err := getFloatValue(result["count"])
+		if err != nil {
+			return nil, fmt.Errorf("bad bucket count: %v", result["count"])
+		}
+		buckets = append(buckets, densityBucket{
+			window: TimeWindow{Earliest: bucketTime, Latest: bucketTime.Add(span)},
+			count:  int64(count),
+		})
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].window.Earliest.Before(buckets[j].window.Earliest)
+	})
+
+	// Timechart buckets snap to the span, so stretch them to cover the whole range without gaps
+	for i := range buckets {
+		if i == 0 {
+			buckets[i].window.Earliest = options.Earliest
+		}
+		if i == len(buckets)-1 {
+			buckets[i].window.Latest = options.Latest
+		} else {
+			buckets[i].window.Latest = buckets[i+1].window.Earliest
+		}
+	}
+	if len(buckets) == 0 {
+		// No events, a single window
+		buckets = append(buckets, densityBucket{window: TimeWindow{Earliest: options.Earliest, Latest: options.Latest}})
+	}
+	return buckets, nil
+}
+
+// String Returns the window as "earliest to latest"
+func (w TimeWindow) String() string {
+	return FormatTime(w.Earliest) + " to " + FormatTime(w.Latest)
+}
+
+// Duration Returns how long the window is
+func (w TimeWindow) Duration() time.Duration {
+	return w.Latest.Sub(w.Earliest)
+}
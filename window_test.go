@@ -0,0 +1,211 @@
+package splunk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitTimeRange(t *testing.T) {
+	start := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	windows := SplitTimeRange(start, start.Add(time.Hour*25), time.Hour*10)
+	require.Equal(t, []TimeWindow{
+		{start, start.Add(time.Hour * 10)},
+		{start.Add(time.Hour * 10), start.Add(time.Hour * 20)},
+		{start.Add(time.Hour * 20), start.Add(time.Hour * 25)},
+	}, windows)
+
+	windows = SplitTimeRangeN(start, start.Add(time.Second*10), 3)
+	require.Len(t, windows, 3)
+	require.Equal(t, start, windows[0].Earliest)
+	require.Equal(t, windows[0].Latest, windows[1].Earliest)
+	require.Equal(t, start.Add(time.Second*10), windows[2].Latest)
+
+	require.Empty(t, SplitTimeRange(start, start, time.Hour))
+}
+
+func TestAdaptiveWindows(t *testing.T) {
+	start := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+	bucket := func(hour int, count int64) densityBucket {
+		return densityBucket{window: TimeWindow{start.Add(time.Hour * time.Duration(hour)), start.Add(time.Hour * time.Duration(hour+1))}, count: count}
+	}
+
+	windows := adaptiveWindows([]densityBucket{bucket(0, 10), bucket(1, 20), bucket(2, 500), bucket(3, 30), bucket(4, 60), bucket(5, 5)}, 100)
+	require.Equal(t, []TimeWindow{
+		{start, start.Add(time.Hour * 2)},
+		{start.Add(time.Hour * 2), start.Add(time.Hour * 3)},
+		{start.Add(time.Hour * 3), start.Add(time.Hour * 6)},
+	}, windows)
+}
+
+func TestSortResultsByTime(t *testing.T) {
+	results := []SearchResult{
+		{"_time": "2020-09-01T00:00:03.000+00:00"},
+		{"_time": "2020-09-01T00:00:01.000+00:00"},
+		{"_time": "2020-09-01T00:00:02.000+00:00"},
+	}
+	sortResultsByTime(results)
+	require.Equal(t, "2020-09-01T00:00:01.000+00:00", results[0]["_time"])
+	require.Equal(t, "2020-09-01T00:00:03.000+00:00", results[2]["_time"])
+}
+
+// windowTestServer is a fake splunk that returns one event per hour of each job's time range, newest first
+type windowTestServer struct {
+	*httptest.Server
+	lock    sync.Mutex
+	jobs    map[string]url.Values
+	deleted int
+}
+
+func newWindowTestServer() *windowTestServer {
+	s := &windowTestServer{jobs: map[string]url.Values{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		path := strings.TrimPrefix(req.URL.Path, "/services/search/jobs")
+		switch {
+		case req.Method == http.MethodPost && path == "":
+			b, _ := ioutil.ReadAll(req.Body)
+			values, _ := url.ParseQuery(string(b))
+			sid := fmt.Sprintf("job_%d", len(s.jobs))
+			s.jobs[sid] = values
+			rw.WriteHeader(http.StatusCreated)
+			rw.Write([]byte(`{"sid":"` + sid + `"}`))
+		case req.Method == http.MethodDelete:
+			s.deleted++
+		case strings.HasSuffix(path, "/results_preview"):
+			job := s.jobs[strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/results_preview")]
+			results := []SearchResult{}
+			if req.URL.Query().Get("offset") == "0" {
+				earliest, _ := ParseTime(job.Get("earliest_time"))
+				latest, _ := ParseTime(job.Get("latest_time"))
+				for t := latest.Add(-time.Hour); !t.Before(earliest); t = t.Add(-time.Hour) {
+					if strings.Contains(job.Get("search"), "timechart") {
+						results = append(results, SearchResult{"_time": FormatTime(t), "count": "50"})
+					} else {
+						results = append(results, SearchResult{"_time": FormatTime(t)})
+					}
+				}
+			}
+			json.NewEncoder(rw).Encode(SearchResults{Results: results})
+		default:
+			rw.Write([]byte(`{"entry":[{"content":{"dispatchState":"DONE"}}]}`))
+		}
+	}))
+	return s
+}
+
+func TestClient_CreateWindowedSearch(t *testing.T) {
+	start := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("fixed windows", func(t *testing.T) {
+		server := newWindowTestServer()
+		defer server.Close()
+		client := &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		}
+
+		search, err := client.CreateWindowedSearch(context.Background(), "index=main", &WindowedSearchOptions{
+			Earliest:    start,
+			Latest:      start.Add(time.Hour * 12),
+			WindowSize:  time.Hour * 3,
+			Concurrency: 2,
+		})
+		require.NoError(t, err)
+		require.Len(t, search.Windows, 4)
+
+		times := []time.Time{}
+		for result := range search.Results() {
+			resultTime, err := ParseAnyTime(result["_time"])
+			require.NoError(t, err)
+			times = append(times, resultTime)
+		}
+		require.NoError(t, search.Err())
+		require.Len(t, times, 12)
+		for i, resultTime := range times {
+			require.True(t, start.Add(time.Hour*time.Duration(i)).Equal(resultTime))
+		}
+		require.Equal(t, 4, server.deleted)
+	})
+
+	t.Run("adaptive windows", func(t *testing.T) {
+		server := newWindowTestServer()
+		defer server.Close()
+		client := &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		}
+
+		// Each hour has 50 events, so 2 hours fit in a window
+		search, err := client.CreateWindowedSearch(context.Background(), "index=main", &WindowedSearchOptions{
+			Earliest:           start,
+			Latest:             start.Add(time.Hour * 6),
+			MaxEventsPerWindow: 100,
+		})
+		require.NoError(t, err)
+		count := 0
+		for range search.Results() {
+			count++
+		}
+		require.NoError(t, search.Err())
+		require.Equal(t, 6, count)
+		require.Contains(t, server.jobs["job_0"].Get("search"), "| timechart span=216s count")
+	})
+
+	t.Run("slow reader", func(t *testing.T) {
+		server := newWindowTestServer()
+		defer server.Close()
+		client := &Client{
+			config: &Config{
+				BaseURL:    server.URL,
+				HTTPClient: http.DefaultClient,
+			},
+		}
+
+		// Each window has more results than the results channel holds
+		search, err := client.CreateWindowedSearch(context.Background(), "index=main", &WindowedSearchOptions{
+			Earliest:    start,
+			Latest:      start.Add(time.Hour * 2000),
+			Windows:     4,
+			Concurrency: 1,
+		})
+		require.NoError(t, err)
+
+		// The next window does not start until the first is read
+		time.Sleep(time.Millisecond * 100)
+		server.lock.Lock()
+		require.Len(t, server.jobs, 1)
+		server.lock.Unlock()
+
+		count := 0
+		for range search.Results() {
+			count++
+		}
+		require.NoError(t, search.Err())
+		require.Equal(t, 2000, count)
+		require.Len(t, server.jobs, 4)
+	})
+
+	t.Run("no split", func(t *testing.T) {
+		client := &Client{config: &Config{}}
+		_, err := client.CreateWindowedSearch(context.Background(), "index=main", &WindowedSearchOptions{Earliest: start, Latest: start.Add(time.Hour)})
+		require.Error(t, err)
+	})
+}